@@ -0,0 +1,65 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// WithAPIKeys требует, чтобы запрос содержал один из ключей keys в заголовке
+// "Authorization: Bearer <key>" или "X-API-Key", и иначе отвечает 401. При
+// пустом keys проверка отключена. Preflight-запросы OPTIONS, проверки
+// /healthz и /readyz и метрики /metrics пропускаются без ключа: Prometheus и
+// балансировщики не передают ключи API, а доступ к ним ограничивают сетью.
+func WithAPIKeys(next http.Handler, keys []string) http.Handler {
+	if len(keys) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions || publicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !ValidAPIKey(requestKey(r), keys) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="downloader"`)
+			writeError(w, http.StatusUnauthorized, codeUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// publicPath сообщает, доступен ли path без ключа API.
+func publicPath(path string) bool {
+	switch path {
+	case "/healthz", "/readyz", "/metrics":
+		return true
+	}
+	return false
+}
+
+// requestKey извлекает ключ из Authorization: Bearer или X-API-Key.
+func requestKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, token, ok := strings.Cut(auth, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// ValidAPIKey сравнивает key со всеми допустимыми ключами keys за постоянное
+// время. Используется и для проверки ключей в gRPC API.
+func ValidAPIKey(key string, keys []string) bool {
+	if key == "" {
+		return false
+	}
+	ok := false
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithAPIKeys(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	tests := []struct {
+		name   string
+		keys   []string
+		method string
+		path   string
+		header [2]string
+		want   int
+	}{
+		{"no keys configured", nil, http.MethodGet, "/tasks", [2]string{}, http.StatusOK},
+		{"missing key", []string{"k1"}, http.MethodGet, "/tasks", [2]string{}, http.StatusUnauthorized},
+		{"wrong key", []string{"k1"}, http.MethodGet, "/tasks", [2]string{"X-API-Key", "k2"}, http.StatusUnauthorized},
+		{"bearer key", []string{"k1", "k2"}, http.MethodGet, "/tasks", [2]string{"Authorization", "Bearer k2"}, http.StatusOK},
+		{"x-api-key", []string{"k1"}, http.MethodGet, "/tasks", [2]string{"X-API-Key", "k1"}, http.StatusOK},
+		{"preflight", []string{"k1"}, http.MethodOptions, "/tasks", [2]string{}, http.StatusOK},
+		{"healthz", []string{"k1"}, http.MethodGet, "/healthz", [2]string{}, http.StatusOK},
+		{"readyz", []string{"k1"}, http.MethodGet, "/readyz", [2]string{}, http.StatusOK},
+		{"metrics", []string{"k1"}, http.MethodGet, "/metrics", [2]string{}, http.StatusOK},
+		{"metrics prefix is not public", []string{"k1"}, http.MethodGet, "/metrics/x", [2]string{}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := WithAPIKeys(ok, tt.keys)
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header[0] != "" {
+				r.Header.Set(tt.header[0], tt.header[1])
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
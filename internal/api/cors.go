@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// CORSConfig задаёт политику CORS для WithCORS.
+type CORSConfig struct {
+	// AllowedOrigins — разрешённые источники. "*" разрешает любой источник.
+	AllowedOrigins []string
+	// AllowedMethods и AllowedHeaders возвращаются в
+	// Access-Control-Allow-Methods и Access-Control-Allow-Headers.
+	AllowedMethods []string
+	AllowedHeaders []string
+	// AllowCredentials добавляет Access-Control-Allow-Credentials: true. В
+	// этом режиме вместо "*" всегда возвращается Origin запроса, как того
+	// требует спецификация.
+	AllowCredentials bool
+}
+
+// DefaultCORSConfig возвращает политику, разрешающую запросы с любого
+// источника без учётных данных.
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
+		AllowedHeaders: []string{"Content-Type", "Idempotency-Key", "Authorization", "X-API-Key"},
+	}
+}
+
+// WithCORS добавляет заголовки CORS согласно cfg и отвечает 204 на
+// preflight-запросы OPTIONS. Если Origin запроса не входит в
+// AllowedOrigins, заголовки не добавляются и браузер отклонит ответ.
+func WithCORS(next http.Handler, cfg CORSConfig) http.Handler {
+	wildcard := slices.Contains(cfg.AllowedOrigins, "*")
+	methods := strings.Join(cfg.AllowedMethods, ",")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := ""
+		switch {
+		case wildcard && !cfg.AllowCredentials:
+			allowed = "*"
+		case origin != "" && (wildcard || slices.Contains(cfg.AllowedOrigins, origin)):
+			allowed = origin
+		}
+		if !wildcard || cfg.AllowCredentials {
+			w.Header().Add("Vary", "Origin")
+		}
+		if allowed != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			if cfg.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
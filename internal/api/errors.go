@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Машиночитаемые коды ошибок в поле "code" ответа.
+const (
+	codeBadRequest       = "bad_request"
+	codeInvalidJSON      = "invalid_json"
+	codeBodyTooLarge     = "body_too_large"
+	codeInvalidTaskID    = "invalid_task_id"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeConflict         = "conflict"
+	codeUnauthorized     = "unauthorized"
+	codeUnavailable      = "unavailable"
+	codeTooManyTasks     = "too_many_tasks"
+	codeInternal         = "internal_error"
+)
+
+// errorResponse — тело ответа с ошибкой.
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// writeError отвечает JSON-объектом {"error": msg, "code": code} со статусом
+// status. Используется вместо http.Error, чтобы клиенты, разбирающие JSON,
+// получали его и при ошибках.
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorResponse{Error: msg, Code: code})
+}
+
+// WithJSONErrors отвечает на запросы, для которых в mux нет маршрута, тем же
+// JSON-форматом ошибок, что и обработчики: ServeMux сам пишет текстовые 404
+// и 405. Заголовок Allow ответа 405 сохраняется.
+func WithJSONErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(&muxErrorWriter{ResponseWriter: w}, r)
+	})
+}
+
+// muxErrorWriter заменяет текстовые ответы 404 и 405 ServeMux на JSON.
+type muxErrorWriter struct {
+	http.ResponseWriter
+	replaced bool
+}
+
+func (w *muxErrorWriter) WriteHeader(status int) {
+	switch status {
+	case http.StatusNotFound:
+		writeError(w.ResponseWriter, status, codeNotFound, "not found")
+	case http.StatusMethodNotAllowed:
+		writeError(w.ResponseWriter, status, codeMethodNotAllowed, "method not allowed")
+	default:
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.replaced = true
+}
+
+func (w *muxErrorWriter) Write(p []byte) (int, error) {
+	if w.replaced {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// contentETag возвращает сильный ETag тела ответа: первые 16 байт SHA-256 в
+// hex. ETag меняется при любом изменении ответа, в том числе статуса или
+// прогресса отдельного файла.
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches сообщает, совпадает ли etag с одним из значений заголовка
+// If-None-Match (список через запятую или "*"). Для If-None-Match сравнение
+// слабое: префикс W/ не учитывается.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, v := range strings.Split(ifNoneMatch, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCacheable отвечает телом JSON body с ETag по его содержимому или 304
+// Not Modified без тела, если клиент прислал совпадающий If-None-Match.
+func writeCacheable(w http.ResponseWriter, r *http.Request, body []byte) {
+	etag := contentETag(body)
+	w.Header().Set("ETag", etag)
+	// клиент может хранить ответ, но обязан перепроверять его при каждом запросе
+	w.Header().Set("Cache-Control", "no-cache")
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
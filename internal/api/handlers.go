@@ -1,114 +1,852 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
 	"hh03012025/internal/manager"
 
 	"hh03012025/internal/model"
+	"hh03012025/internal/util"
 )
 
-// NewCreateTaskHandler возвращает HTTP‑обработчик для создания новой задачи.
-// Ожидает JSON‑тело с полем "urls" — массивом ссылок. На успех отдаёт 202
-// и идентификатор задачи. При ошибке возвращает 400 или 500.
-func NewCreateTaskHandler(m *manager.Manager) http.HandlerFunc {
+// urlEntry — элемент массива "urls" в запросе на создание задачи. Может быть
+// как строкой со ссылкой, так и объектом {"url": "...", "headers": {...},
+// "mirrors": [...], "accept": "...", "expected_content_type": "...",
+// "sha256": "...", "filename": "..."}.
+type urlEntry struct {
+	URL                 string            `json:"url"`
+	Headers             map[string]string `json:"headers,omitempty"`
+	Mirrors             []string          `json:"mirrors,omitempty"`
+	Accept              string            `json:"accept,omitempty"`
+	ExpectedContentType string            `json:"expected_content_type,omitempty"`
+	SHA256              string            `json:"sha256,omitempty"`
+	Filename            string            `json:"filename,omitempty"`
+}
+
+// UnmarshalJSON принимает как строку, так и объект, чтобы старые клиенты,
+// присылающие массив строк, продолжали работать.
+func (e *urlEntry) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*e = urlEntry{URL: s}
+		return nil
+	}
+	type plain urlEntry
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*e = urlEntry(p)
+	return nil
+}
+
+// busyRetryAfter — значение Retry-After в ответе 429 при достигнутом лимите
+// незавершённых задач.
+const busyRetryAfter = 10 * time.Second
+
+// NewCreateTaskHandler возвращает HTTP‑обработчик POST /tasks для создания новой задачи.
+// Ожидает JSON‑тело с полем "urls" — массивом ссылок (строк или объектов с
+// полями "url", "headers", "mirrors", "accept", "expected_content_type",
+// "sha256" и "filename"), необязательным "callback_url", на который
+// после завершения задачи отправляется POST с её итогами, "priority"
+// (high, normal или low; по умолчанию normal) и "extract" — распаковать
+// скачанные архивы zip и tar.gz в каталог задачи, "max_concurrent" — лимит
+// одновременно скачиваемых файлов задачи, "dest_subdir" — относительный
+// каталог внутри каталога загрузок вместо каталога с ID задачи (абсолютный
+// путь или выход за его пределы дают 400), "labels" — произвольные метки
+// задачи «ключ — значение», "start_at" — время отложенного запуска в
+// RFC 3339 (задача получает статус "scheduled", а прошедшее время даёт 400,
+// если не включён немедленный запуск таких задач), "deadline" (RFC 3339) или
+// "max_duration" ("30m") — срок выполнения задачи, после которого
+// незавершённые файлы получают статус "error". На успех отдаёт
+// 202, идентификатор задачи, заголовок Location с путём /tasks/{id} и
+// полный адрес статуса задачи в "status_url". При ошибке возвращает 400 или
+// 500.
+//
+// Заголовок Idempotency-Key защищает от дублей при повторной отправке: если
+// задача с таким ключом уже создана, возвращается её ID с кодом 200. Если
+// ключ повторно использован с другим списком URL, возвращается 409. При
+// включённом поиске дубликатов для набора URL, совпадающего с незавершённой
+// задачей, возвращается её ID с кодом 200 и заголовком X-Task-Deduplicated.
+// Если достигнут лимит незавершённых задач, возвращается 429 с заголовком
+// Retry-After.
+//
+// С параметром dry_run=true задача не создаётся: URL только проверяются, и
+// возвращаются результаты по каждому из них (см. writeDryRun).
+//
+// Вместо JSON тело может быть манифестом text/plain (URL по одному на строку)
+// или text/csv (url,sha256,filename); параметры задачи тогда передаются в
+// строке запроса (см. manifestTaskSpec).
+//
+// Тело запроса ограничено maxBodyBytes байтами (0 — без ограничения); при
+// превышении возвращается 413.
+func NewCreateTaskHandler(m *manager.Manager, maxBodyBytes int64) http.HandlerFunc {
 	type request struct {
-		URLs []string `json:"urls"`
+		URLs        []urlEntry `json:"urls"`
+		CallbackURL string     `json:"callback_url"`
+		// FileCallbackURL получает уведомление о завершении каждого файла.
+		FileCallbackURL string `json:"file_callback_url"`
+		// FilenameTemplate — шаблон имён файлов задачи (text/template).
+		FilenameTemplate string `json:"filename_template"`
+		Priority         string `json:"priority"`
+		Extract          bool   `json:"extract"`
+		// MaxConcurrent переопределяет общий лимит параллельных скачиваний
+		// файлов задачи.
+		MaxConcurrent int               `json:"max_concurrent"`
+		DestSubdir    string            `json:"dest_subdir"`
+		Labels        map[string]string `json:"labels"`
+		StartAt       *time.Time        `json:"start_at"`
+		Deadline      *time.Time        `json:"deadline"`
+		// MaxDuration — срок задачи в формате time.ParseDuration ("30m").
+		MaxDuration string `json:"max_duration"`
 	}
 	type response struct {
-		TaskID string `json:"task_id"`
-		Status string `json:"status"`
+		TaskID    string       `json:"task_id"`
+		Status    model.Status `json:"status"`
+		StatusURL string       `json:"status_url"`
 	}
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		dryRun, err := queryBool(r, "dry_run")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
 			return
 		}
-		var req request
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "invalid JSON", http.StatusBadRequest)
-			return
+		if maxBodyBytes > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
 		}
-		// trim whitespace and filter empty entries
-		clean := make([]string, 0, len(req.URLs))
-		for _, s := range req.URLs {
-			s = strings.TrimSpace(s)
-			if s != "" {
-				clean = append(clean, s)
+		var spec manager.TaskSpec
+		if ct := manifestType(r); ct != "" {
+			files, err := parseManifest(r.Body, ct)
+			if err != nil {
+				writeBodyError(w, err, codeBadRequest, "invalid manifest: "+err.Error())
+				return
+			}
+			if spec, err = manifestTaskSpec(r, files); err != nil {
+				writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+				return
+			}
+		} else {
+			var req request
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeBodyError(w, err, codeInvalidJSON, "invalid JSON")
+				return
 			}
+			// trim whitespace and filter empty entries
+			clean := make([]manager.FileSpec, 0, len(req.URLs))
+			for _, e := range req.URLs {
+				u := strings.TrimSpace(e.URL)
+				if u != "" {
+					clean = append(clean, manager.FileSpec{URL: u, Headers: e.Headers, Mirrors: trimURLs(e.Mirrors),
+						Accept:              strings.TrimSpace(e.Accept),
+						ExpectedContentType: strings.TrimSpace(e.ExpectedContentType),
+						SHA256:              strings.TrimSpace(e.SHA256),
+						Filename:            strings.TrimSpace(e.Filename)})
+				}
+			}
+			var startAt, deadline time.Time
+			if req.StartAt != nil {
+				startAt = *req.StartAt
+			}
+			if req.Deadline != nil {
+				deadline = *req.Deadline
+			}
+			var maxDuration time.Duration
+			if req.MaxDuration != "" {
+				if maxDuration, err = time.ParseDuration(req.MaxDuration); err != nil {
+					writeError(w, http.StatusBadRequest, codeBadRequest, "invalid max_duration: "+err.Error())
+					return
+				}
+			}
+			spec = manager.TaskSpec{
+				Files:            clean,
+				CallbackURL:      strings.TrimSpace(req.CallbackURL),
+				FileCallbackURL:  strings.TrimSpace(req.FileCallbackURL),
+				FilenameTemplate: req.FilenameTemplate,
+				Priority:         strings.TrimSpace(req.Priority),
+				Extract:          req.Extract,
+				MaxConcurrent:    req.MaxConcurrent,
+				DestSubdir:       req.DestSubdir,
+				Labels:           req.Labels,
+				StartAt:          startAt,
+				Deadline:         deadline,
+				MaxDuration:      maxDuration,
+			}
+		}
+		spec.IdempotencyKey = strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+		// спан задачи продолжает трассировку клиента из заголовка traceparent
+		traceCtx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		spec.TraceParent = trace.SpanContextFromContext(traceCtx)
+		if dryRun {
+			writeDryRun(w, r, m, spec)
+			return
+		}
+		task, created, err := m.AddTask(spec)
+		if errors.Is(err, manager.ErrIdempotencyConflict) {
+			writeError(w, http.StatusConflict, codeConflict, err.Error())
+			return
+		}
+		if errors.Is(err, manager.ErrTooManyTasks) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(busyRetryAfter/time.Second)))
+			writeError(w, http.StatusTooManyRequests, codeTooManyTasks, err.Error())
+			return
+		}
+		if errors.Is(err, manager.ErrDuplicateTask) {
+			w.Header().Set("X-Task-Deduplicated", "true")
+			err = nil
 		}
-		task, err := m.AddTask(clean)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
 			return
 		}
+		code := http.StatusAccepted
+		if !created {
+			code = http.StatusOK
+		}
+		location := "/tasks/" + url.PathEscape(task.ID)
+		w.Header().Set("Location", location)
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusAccepted)
-		_ = json.NewEncoder(w).Encode(response{TaskID: task.ID, Status: task.Status})
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(response{TaskID: task.ID, Status: task.Status, StatusURL: absoluteURL(r, location)})
+	}
+}
+
+// absoluteURL возвращает абсолютный URL пути path на этом сервере: хост
+// берётся из запроса r, схема — https для TLS-соединений или из заголовка
+// X-Forwarded-Proto, который выставляет обратный прокси.
+func absoluteURL(r *http.Request, path string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
 	}
+	if proto := strings.ToLower(r.Header.Get("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + path
 }
 
-// NewGetTaskHandler возвращает обработчик, который возвращает статус задачи по ID.
-// Если задача не найдена, отвечает 404.
-func NewGetTaskHandler(m *manager.Manager) http.HandlerFunc {
+// writeBodyError отвечает на ошибку чтения тела запроса: 413 при превышении
+// лимита размера, иначе 400 с кодом code и сообщением msg.
+func writeBodyError(w http.ResponseWriter, err error, code, msg string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, codeBodyTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, code, msg)
+}
+
+// writeDryRun отвечает на пробное создание задачи: проверяет параметры
+// задачи (ошибка — 400) и каждый URL, а при check_reachable=true — и его
+// доступность запросом HEAD. Отвечает 200 с полем valid, равным true, если
+// корректны все URL, и результатами по URL в поле urls.
+func writeDryRun(w http.ResponseWriter, r *http.Request, m *manager.Manager, spec manager.TaskSpec) {
 	type response struct {
-		ID        string            `json:"id"`
-		Status    string            `json:"status"`
-		Completed int               `json:"completed"`
-		Total     int               `json:"total"`
-		Files     []model.FileState `json:"files"`
-		CreatedAt time.Time         `json:"created_at"`
-		UpdatedAt time.Time         `json:"updated_at"`
+		Valid bool               `json:"valid"`
+		URLs  []manager.URLCheck `json:"urls"`
+	}
+	reachable, err := queryBool(r, "check_reachable")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	checks, err := m.ValidateTask(r.Context(), spec, reachable)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	resp := response{Valid: true, URLs: checks}
+	for _, c := range checks {
+		if !c.Valid {
+			resp.Valid = false
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// queryBool разбирает логический параметр запроса name; отсутствующий
+// параметр равен false.
+func queryBool(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %q", name, v)
 	}
+	return b, nil
+}
+
+// taskResponse — представление задачи в ответах API. Используется и
+// GET‑обработчиком, и потоком событий, чтобы клиенты разбирали один формат.
+type taskResponse struct {
+	ID       string       `json:"id"`
+	Status   model.Status `json:"status"`
+	Priority string       `json:"priority,omitempty"`
+	Extract  bool         `json:"extract,omitempty"`
+	// MaxConcurrent — собственный лимит параллельных скачиваний задачи.
+	MaxConcurrent int          `json:"max_concurrent,omitempty"`
+	DestSubdir    string       `json:"dest_subdir,omitempty"`
+	Layout        string       `json:"layout,omitempty"`
+	Labels        model.Labels `json:"labels,omitempty"`
+	StartAt       *time.Time   `json:"start_at,omitempty"`
+	Deadline      *time.Time   `json:"deadline,omitempty"`
+	Completed     int          `json:"completed"`
+	Total         int          `json:"total"`
+	// Сводка по байтам: TotalBytes — сумма известных размеров файлов,
+	// DownloadedBytes — сумма скачанных байт, Percent — доля скачанного по
+	// файлам с известным размером. Approximate означает, что размер части
+	// незавершённых файлов неизвестен и они не учтены в Percent.
+	TotalBytes      int64   `json:"total_bytes"`
+	DownloadedBytes int64   `json:"downloaded_bytes"`
+	Percent         float64 `json:"percent"`
+	Approximate     bool    `json:"percent_approximate,omitempty"`
+	// Speed — суммарная скорость скачивания файлов задачи, байт в секунду.
+	// ETA — оценка оставшегося времени в секундах; не заполняется, пока
+	// размер части файлов неизвестен.
+	Speed     int64             `json:"speed_bps"`
+	ETA       int64             `json:"eta_seconds,omitempty"`
+	Files     []model.FileState `json:"files"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// newTaskResponse строит ответ по задаче, подсчитывая завершённые файлы.
+func newTaskResponse(task *model.Task) taskResponse {
+	completed := 0
+	var total, downloaded, known, speed int64
+	approximate := false
+	for _, f := range task.Files {
+		if f.Status == model.StatusCompleted {
+			completed++
+		}
+		downloaded += f.Downloaded
+		speed += f.Speed
+		if f.Size > 0 || f.Status == model.StatusCompleted {
+			total += f.Size
+			known += min(f.Downloaded, f.Size)
+		} else if !manager.IsTerminal(f.Status) && f.Status != model.StatusError {
+			approximate = true
+		}
+	}
+	percent := 0.0
+	if total > 0 {
+		percent = math.Round(float64(known)/float64(total)*1000) / 10
+	} else if completed == len(task.Files) {
+		percent = 100
+	}
+	var eta int64
+	if !approximate && speed > 0 && total > known {
+		eta = int64(math.Ceil(float64(total-known) / float64(speed)))
+	}
+	return taskResponse{
+		ID:       task.ID,
+		Status:   task.Status,
+		Priority: task.Priority,
+		Extract:  task.Extract,
+
+		MaxConcurrent: task.MaxConcurrent,
+		DestSubdir:    task.DestSubdir,
+		Layout:        task.Layout,
+		Labels:        task.Labels,
+		StartAt:       task.StartAt,
+		Deadline:      task.Deadline,
+		Completed:     completed,
+		Total:         len(task.Files),
+
+		TotalBytes:      total,
+		DownloadedBytes: downloaded,
+		Percent:         percent,
+		Approximate:     approximate,
+		Speed:           speed,
+		ETA:             eta,
+
+		Files:     task.Files,
+		CreatedAt: task.CreatedAt,
+		UpdatedAt: task.UpdatedAt,
+	}
+}
+
+// NewGetTaskHandler возвращает обработчик GET /tasks/{id}, который возвращает
+// статус задачи по ID. Для ID неверного формата отвечает 400, если задача не
+// найдена — 404. Ответ содержит ETag по содержимому; на запрос с совпадающим
+// If-None-Match отвечает 304 без тела.
+//
+// Параметр wait (длительность вроде "30s" или число секунд) включает долгий
+// опрос: ответ откладывается, пока задача не изменится или не истечёт время
+// ожидания, но не дольше maxWait. Для завершённой задачи ответ отдаётся
+// сразу. Нулевой maxWait отключает ожидание.
+func NewGetTaskHandler(m *manager.Manager, maxWait time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// expect /tasks/{id}
-		parts := strings.Split(r.URL.Path, "/")
-		if len(parts) != 3 || parts[2] == "" {
-			http.Error(w, "task id missing", http.StatusBadRequest)
+		id, ok := taskID(w, r)
+		if !ok {
 			return
 		}
-		id := parts[2]
-		task, ok := m.GetTask(id)
+		wait, err := queryDuration(r, "wait")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+			return
+		}
+		task, ok := waitTask(r.Context(), m, id, min(wait, maxWait))
 		if !ok {
-			http.NotFound(w, r)
+			writeError(w, http.StatusNotFound, codeNotFound, "task not found")
+			return
+		}
+		body, err := json.Marshal(newTaskResponse(task))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 			return
 		}
-		completed := 0
-		for _, f := range task.Files {
-			if f.Status == "completed" {
-				completed++
+		writeCacheable(w, r, append(body, '\n'))
+	}
+}
+
+// waitTask возвращает задачу id, дождавшись изменения её UpdatedAt, но не
+// дольше wait. Ожидание прерывается при отключении клиента (отмене ctx), а
+// для завершённой задачи не начинается. Возвращает false, если задачи нет.
+func waitTask(ctx context.Context, m *manager.Manager, id string, wait time.Duration) (*model.Task, bool) {
+	if wait <= 0 {
+		return m.GetTask(id)
+	}
+	// подписываемся до чтения задачи, чтобы не пропустить изменение между ними
+	updates, unsubscribe, ok := m.Subscribe(id)
+	if !ok {
+		return nil, false
+	}
+	defer unsubscribe()
+	task, ok := m.GetTask(id)
+	if !ok || manager.IsTerminal(task.Status) {
+		return task, ok
+	}
+	since := task.UpdatedAt
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return task, true
+		case <-timer.C:
+			return m.GetTask(id)
+		case <-updates:
+		}
+		cur, ok := m.GetTask(id)
+		if !ok || !cur.UpdatedAt.Equal(since) {
+			return cur, ok
+		}
+	}
+}
+
+// queryDuration разбирает параметр запроса name как длительность ("30s",
+// "1m") или целое число секунд. Отсутствующий параметр даёт 0.
+func queryDuration(r *http.Request, name string) (time.Duration, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		secs, convErr := strconv.Atoi(v)
+		if convErr != nil {
+			return 0, fmt.Errorf("%s must be a duration, got %q", name, v)
+		}
+		d = time.Duration(secs) * time.Second
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s must not be negative, got %q", name, v)
+	}
+	return d, nil
+}
+
+// taskID возвращает ID задачи из шаблона пути {id}. Если ID не похож на
+// выданный сервисом, отвечает 400 и возвращает false.
+func taskID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.PathValue("id")
+	if !util.ValidID(id) {
+		writeError(w, http.StatusBadRequest, codeInvalidTaskID, "invalid task id")
+		return "", false
+	}
+	return id, true
+}
+
+// Размер страницы списка задач: по умолчанию и максимально допустимый.
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// NewListTasksHandler возвращает обработчик GET /tasks, отдающий задачи от
+// новых к старым. Параметр status — один или несколько общих статусов через
+// запятую — оставляет только задачи с этими статусами; limit (по умолчанию
+// 100, не больше 1000) и offset задают страницу, а total в ответе — число
+// задач, подходящих под фильтр. Параметр label вида ключ:значение (можно
+// повторять) оставляет задачи со всеми указанными метками. Для неизвестного
+// статуса, метки без ':' или некорректных limit/offset отвечает 400.
+func NewListTasksHandler(m *manager.Manager) http.HandlerFunc {
+	type response struct {
+		Tasks  []taskResponse `json:"tasks"`
+		Total  int            `json:"total"`
+		Limit  int            `json:"limit"`
+		Offset int            `json:"offset"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		opts := manager.ListOptions{Limit: defaultListLimit}
+		if v := q.Get("status"); v != "" {
+			for _, s := range strings.Split(v, ",") {
+				status := model.Status(strings.TrimSpace(s))
+				if !status.Valid() {
+					writeError(w, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("unknown status %q", s))
+					return
+				}
+				opts.Statuses = append(opts.Statuses, status)
+			}
+		}
+		for _, v := range q["label"] {
+			key, value, ok := strings.Cut(v, ":")
+			if !ok || key == "" {
+				writeError(w, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("label selector %q must look like key:value", v))
+				return
 			}
+			if opts.Labels == nil {
+				opts.Labels = make(map[string]string)
+			}
+			opts.Labels[key] = value
+		}
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > maxListLimit {
+				writeError(w, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxListLimit))
+				return
+			}
+			opts.Limit = n
 		}
-		resp := response{
-			ID:        task.ID,
-			Status:    task.Status,
-			Completed: completed,
-			Total:     len(task.Files),
-			Files:     task.Files,
-			CreatedAt: task.CreatedAt,
-			UpdatedAt: task.UpdatedAt,
+		if v := q.Get("offset"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeError(w, http.StatusBadRequest, codeBadRequest, "offset must be a non-negative integer")
+				return
+			}
+			opts.Offset = n
+		}
+		tasks, total := m.ListTasks(opts)
+		resp := response{Tasks: make([]taskResponse, len(tasks)), Total: total, Limit: opts.Limit, Offset: opts.Offset}
+		for i, t := range tasks {
+			resp.Tasks[i] = newTaskResponse(t)
 		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(resp)
 	}
 }
 
-// WithCORS добавляет разрешающие CORS‑заголовки. Позволяет всем доменам
-// отправлять GET, POST и OPTIONS запросы. Обёрнутый хендлер должен сам
-// обрабатывать остальные методы.
-func WithCORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
+// NewBatchStatusHandler возвращает обработчик POST /tasks/status, который
+// отдаёт статусы нескольких задач одним ответом: дашборду, следящему за
+// многими задачами, не нужен отдельный GET на каждую. Ожидает JSON
+// {"ids": ["...", ...]} (не больше 1000 ID) и отвечает задачами в порядке
+// запроса в том же виде, что GET /tasks/{id}; неизвестные ID и ID неверного
+// формата перечисляются в "not_found". Тело ограничено maxBodyBytes байтами
+// (0 — без ограничения).
+func NewBatchStatusHandler(m *manager.Manager, maxBodyBytes int64) http.HandlerFunc {
+	type request struct {
+		IDs []string `json:"ids"`
+	}
+	type response struct {
+		Tasks    []taskResponse `json:"tasks"`
+		NotFound []string       `json:"not_found"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if maxBodyBytes > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		}
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, err, codeInvalidJSON, "invalid JSON")
 			return
 		}
-		next.ServeHTTP(w, r)
-	})
+		if len(req.IDs) > maxListLimit {
+			writeError(w, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("at most %d ids per request", maxListLimit))
+			return
+		}
+		resp := response{Tasks: make([]taskResponse, 0, len(req.IDs)), NotFound: []string{}}
+		for _, id := range req.IDs {
+			id = strings.TrimSpace(id)
+			task, ok := m.GetTask(id)
+			if !ok {
+				resp.NotFound = append(resp.NotFound, id)
+				continue
+			}
+			resp.Tasks = append(resp.Tasks, newTaskResponse(task))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// NewFileHandler возвращает обработчик GET /tasks/{id}/files/{index}, который
+// отдаёт скачанный файл с Content-Type (по расширению или содержимому) и
+// Content-Disposition: attachment. Для неизвестной задачи, индекса вне
+// диапазона или ещё не скачанного файла отвечает 404, для нечислового
+// индекса — 400.
+func NewFileHandler(m *manager.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		index, err := strconv.Atoi(r.PathValue("index"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "invalid file index")
+			return
+		}
+		id, ok := taskID(w, r)
+		if !ok {
+			return
+		}
+		path, err := m.FilePath(id, index)
+		if err != nil {
+			writeError(w, http.StatusNotFound, codeNotFound, "file not found")
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			writeError(w, http.StatusNotFound, codeNotFound, "file not found")
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			writeError(w, http.StatusNotFound, codeNotFound, "file not found")
+			return
+		}
+		name := filepath.Base(path)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		http.ServeContent(w, r, name, info.ModTime(), f)
+	}
+}
+
+// NewPauseTaskHandler возвращает обработчик POST /tasks/{id}/pause. Отвечает
+// обновлённой задачей, 404 для неизвестной задачи и 409 для завершённой.
+func NewPauseTaskHandler(m *manager.Manager) http.HandlerFunc {
+	return taskActionHandler(m.PauseTask)
+}
+
+// NewResumeTaskHandler возвращает обработчик POST /tasks/{id}/resume.
+// Отвечает обновлённой задачей или 404 для неизвестной задачи.
+func NewResumeTaskHandler(m *manager.Manager) http.HandlerFunc {
+	return taskActionHandler(m.ResumeTask)
+}
+
+// NewRetryTaskHandler возвращает обработчик POST /tasks/{id}/retry, повторно
+// ставящий в очередь файлы с ошибкой. Отвечает обновлённой задачей, 404 для
+// неизвестной задачи и 409, если файлов с ошибкой нет.
+func NewRetryTaskHandler(m *manager.Manager) http.HandlerFunc {
+	return taskActionHandler(m.RetryTask)
+}
+
+// NewCancelTaskHandler возвращает обработчик POST /tasks/{id}/cancel,
+// останавливающий скачивание без удаления задачи и файлов. Отвечает
+// обновлённой задачей, 404 для неизвестной задачи и 409 для завершённой.
+func NewCancelTaskHandler(m *manager.Manager) http.HandlerFunc {
+	return taskActionHandler(m.CancelTask)
+}
+
+// taskActionHandler оборачивает действие над задачей с ID из пути в
+// обработчик, отвечающий итоговым состоянием задачи.
+func taskActionHandler(action func(id string) (*model.Task, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := taskID(w, r)
+		if !ok {
+			return
+		}
+		task, err := action(id)
+		switch {
+		case errors.Is(err, manager.ErrTaskNotFound):
+			writeError(w, http.StatusNotFound, codeNotFound, "task not found")
+			return
+		case errors.Is(err, manager.ErrTaskFinished), errors.Is(err, manager.ErrNoFailedFiles),
+			errors.Is(err, manager.ErrSecretsLost):
+			writeError(w, http.StatusConflict, codeConflict, err.Error())
+			return
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(newTaskResponse(task))
+	}
+}
+
+// NewTaskEventsHandler возвращает обработчик Server-Sent Events для
+// /tasks/{id}/events. Сразу отправляет текущее состояние задачи, затем —
+// событие "status" после каждого изменения в том же формате, что и GET.
+// Поток закрывается, когда задача переходит в терминальное состояние,
+// клиент отключается или закрывается stop. http.Server.Shutdown не отменяет
+// контексты запросов и ждёт их завершения, поэтому stop закрывается при
+// остановке сервера (см. http.Server.RegisterOnShutdown): иначе поток
+// приостановленной или долгой задачи не дал бы сервису завершиться. Если
+// задача не найдена, отвечает 404.
+func NewTaskEventsHandler(m *manager.Manager, stop <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeError(w, http.StatusInternalServerError, codeInternal, "streaming unsupported")
+			return
+		}
+		id, ok := taskID(w, r)
+		if !ok {
+			return
+		}
+		updates, unsubscribe, ok := m.Subscribe(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, codeNotFound, "task not found")
+			return
+		}
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		for {
+			task, ok := m.GetTask(id)
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(newTaskResponse(task))
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+			if manager.IsTerminal(task.Status) {
+				return
+			}
+			select {
+			case <-r.Context().Done():
+				return
+			case <-stop:
+				return
+			case <-updates:
+			}
+		}
+	}
+}
+
+// NewStatsHandler возвращает обработчик GET /stats со сводной статистикой:
+// число задач и файлов по статусам, скачанные байты, длина очереди и число
+// воркеров. В отличие от /metrics, ответ — JSON-снимок для дашбордов без
+// Prometheus.
+func NewStatsHandler(m *manager.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(m.Stats())
+	}
+}
+
+// NewWorkersInfoHandler возвращает обработчик GET /admin/workers для отладки
+// зависаний: число воркеров, длина и ёмкость очереди по приоритетам и
+// задания, которые воркеры обрабатывают сейчас, со временем обработки.
+func NewWorkersInfoHandler(m *manager.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(m.WorkersInfo())
+	}
+}
+
+// NewWorkersHandler возвращает обработчик POST /admin/workers, изменяющий
+// число воркеров. Ожидает JSON {"workers": n} с n > 0 и отвечает новым
+// числом воркеров. При уменьшении занятые воркеры завершаются после
+// текущего скачивания.
+func NewWorkersHandler(m *manager.Manager) http.HandlerFunc {
+	type request struct {
+		Workers int `json:"workers"`
+	}
+	type response struct {
+		Workers int `json:"workers"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidJSON, "invalid JSON")
+			return
+		}
+		n, err := m.SetWorkers(req.Workers)
+		if errors.Is(err, manager.ErrWorkersNotStarted) {
+			writeError(w, http.StatusServiceUnavailable, codeUnavailable, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response{Workers: n})
+	}
+}
+
+// NewSnapshotHandler возвращает обработчик POST /admin/snapshot: он
+// немедленно записывает состояние задач в хранилище и возвращает число
+// записанных задач.
+func NewSnapshotHandler(m *manager.Manager) http.HandlerFunc {
+	type response struct {
+		Tasks int `json:"tasks"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		n, err := m.Snapshot()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response{Tasks: n})
+	}
+}
+
+// NewHealthHandler возвращает обработчик проверки живости (liveness): он
+// всегда отвечает 200, пока процесс работает.
+func NewHealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}
+}
+
+// NewReadyHandler возвращает обработчик проверки готовности (readiness).
+// Пока менеджер находится в режиме draining, отвечает 503, чтобы
+// оркестратор перестал направлять новый трафик; иначе — 200.
+func NewReadyHandler(m *manager.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if m.Draining() {
+			writeError(w, http.StatusServiceUnavailable, codeUnavailable, "draining")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ready"))
+	}
+}
+
+// trimURLs обрезает пробелы и отбрасывает пустые строки.
+func trimURLs(urls []string) []string {
+	var out []string
+	for _, u := range urls {
+		if u = strings.TrimSpace(u); u != "" {
+			out = append(out, u)
+		}
+	}
+	return out
 }
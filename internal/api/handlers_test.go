@@ -0,0 +1,301 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"hh03012025/internal/manager"
+	"hh03012025/internal/model"
+)
+
+// newTestManager создаёт менеджер с каталогом загрузок во временном
+// каталоге теста и запускает workers воркеров (0 — без воркеров). Источники
+// в тестах — httptest-серверы на 127.0.0.1, поэтому приватные адреса
+// разрешены.
+func newTestManager(t *testing.T, workers int, cfg manager.Config) *manager.Manager {
+	t.Helper()
+	cfg.DownloadDir = t.TempDir()
+	cfg.AllowPrivateIPs = true
+	m := manager.NewManager(100, cfg, nil)
+	if workers > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.StartWorkers(ctx, workers)
+		t.Cleanup(func() {
+			cancel()
+			m.Wait(context.Background())
+		})
+	}
+	return m
+}
+
+// newTestMux регистрирует обработчики задач так же, как main.
+func newTestMux(m *manager.Manager) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tasks", NewCreateTaskHandler(m, 1<<20))
+	mux.HandleFunc("GET /tasks", NewListTasksHandler(m))
+	mux.HandleFunc("GET /tasks/{id}", NewGetTaskHandler(m, time.Second))
+	mux.HandleFunc("POST /tasks/{id}/retry", NewRetryTaskHandler(m))
+	mux.HandleFunc("POST /tasks/{id}/cancel", NewCancelTaskHandler(m))
+	return mux
+}
+
+// serve выполняет запрос к h и возвращает ответ.
+func serve(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// decodeJSON разбирает тело ответа w как JSON-объект.
+func decodeJSON(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var v map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("response is not JSON: %v; body %q", err, w.Body.String())
+	}
+	return v
+}
+
+// waitFinished ждёт перехода задачи id в терминальное состояние.
+func waitFinished(t *testing.T, m *manager.Manager, id string) *model.Task {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		task, ok := m.GetTask(id)
+		if !ok {
+			t.Fatalf("task %s not found", id)
+		}
+		if manager.IsTerminal(task.Status) {
+			return task
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task %s not finished, status %q", id, task.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// createTask создаёт задачу через POST /tasks и возвращает её ID.
+func createTask(t *testing.T, h http.Handler, body string) string {
+	t.Helper()
+	w := serve(h, http.MethodPost, "/tasks", body)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("POST /tasks: status %d, body %q", w.Code, w.Body.String())
+	}
+	id, _ := decodeJSON(t, w)["task_id"].(string)
+	return id
+}
+
+func TestGetTaskRetryFields(t *testing.T) {
+	var calls atomic.Int32
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// /flaky отвечает 503 на первый запрос
+		if r.URL.Path == "/flaky" && calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer src.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		wantRetry float64
+	}{
+		{"first attempt succeeds", "/ok", 0},
+		{"one retry after 503", "/flaky", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, 1, manager.Config{DownloadRetries: 2, RetryBackoff: time.Millisecond})
+			mux := newTestMux(m)
+			id := createTask(t, mux, `{"urls":["`+src.URL+tt.path+`"]}`)
+			waitFinished(t, m, id)
+
+			w := serve(mux, http.MethodGet, "/tasks/"+id, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET: status %d", w.Code)
+			}
+			files, _ := decodeJSON(t, w)["files"].([]any)
+			if len(files) != 1 {
+				t.Fatalf("files = %v", files)
+			}
+			f := files[0].(map[string]any)
+			if f["status"] != string(model.StatusCompleted) {
+				t.Errorf("status = %v, want completed", f["status"])
+			}
+			if got, ok := f["retry_count"].(float64); !ok || got != tt.wantRetry {
+				t.Errorf("retry_count = %v, want %v", f["retry_count"], tt.wantRetry)
+			}
+			at, ok := f["last_attempt_at"].(string)
+			if !ok {
+				t.Fatalf("last_attempt_at missing: %v", f)
+			}
+			if _, err := time.Parse(time.RFC3339Nano, at); err != nil {
+				t.Errorf("last_attempt_at %q: %v", at, err)
+			}
+		})
+	}
+}
+
+func TestRetryTaskDoesNotCountAsRetry(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer src.Close()
+
+	m := newTestManager(t, 1, manager.Config{})
+	mux := newTestMux(m)
+	id := createTask(t, mux, `{"urls":["`+src.URL+`/file"]}`)
+	if task := waitFinished(t, m, id); task.Files[0].Status != model.StatusError {
+		t.Fatalf("status = %q, want error", task.Files[0].Status)
+	}
+	fail.Store(false)
+	if w := serve(mux, http.MethodPost, "/tasks/"+id+"/retry", ""); w.Code != http.StatusOK {
+		t.Fatalf("retry: status %d, body %q", w.Code, w.Body.String())
+	}
+	task := waitFinished(t, m, id)
+	if f := task.Files[0]; f.Status != model.StatusCompleted || f.RetryCount != 0 || f.LastAttemptAt == nil {
+		t.Errorf("after manual retry: status %q, retry_count %d, last_attempt_at %v; want completed, 0, set",
+			f.Status, f.RetryCount, f.LastAttemptAt)
+	}
+}
+
+func TestCreateTaskLimits(t *testing.T) {
+	urls := func(n int) string {
+		var list []string
+		for i := range n {
+			list = append(list, fmt.Sprintf(`"https://example.com/%d"`, i))
+		}
+		return `{"urls":[` + strings.Join(list, ",") + `]}`
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+	}{
+		{"within limits", "", urls(2), http.StatusAccepted, ""},
+		{"too many URLs", "", urls(3), http.StatusBadRequest, "bad_request"},
+		{"body too large", "", `{"urls":["https://example.com/` + strings.Repeat("a", 300) + `"]}`, http.StatusRequestEntityTooLarge, "body_too_large"},
+		{"manifest too large", "text/plain", strings.Repeat("https://example.com/file\n", 20), http.StatusRequestEntityTooLarge, "body_too_large"},
+		{"manifest with too many URLs", "text/plain", "https://h/1\nhttps://h/2\nhttps://h/3\n", http.StatusBadRequest, "bad_request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, 0, manager.Config{MaxURLsPerTask: 2})
+			h := NewCreateTaskHandler(m, 256)
+			r := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			if code := decodeJSON(t, w)["code"]; code != tt.wantCode {
+				t.Errorf("code = %v, want %q", code, tt.wantCode)
+			}
+			if _, n := m.ListTasks(manager.ListOptions{}); n != 0 {
+				t.Errorf("%d tasks created by a rejected request", n)
+			}
+		})
+	}
+}
+
+func TestWithJSONErrors(t *testing.T) {
+	h := WithJSONErrors(newTestMux(newTestManager(t, 0, manager.Config{})))
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantCode   string
+		wantAllow  string
+	}{
+		{"unknown path", http.MethodGet, "/nope", http.StatusNotFound, "not_found", ""},
+		{"unknown nested path", http.MethodGet, "/tasks/x/y/z", http.StatusNotFound, "not_found", ""},
+		{"wrong method", http.MethodDelete, "/tasks", http.StatusMethodNotAllowed, "method_not_allowed", "GET, HEAD, POST"},
+		{"wrong method on task", http.MethodPut, "/tasks/abc/cancel", http.StatusMethodNotAllowed, "method_not_allowed", "POST"},
+		{"matched route", http.MethodGet, "/tasks", http.StatusOK, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(h, tt.method, tt.path, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			body := decodeJSON(t, w)
+			if body["code"] != tt.wantCode || body["error"] == "" {
+				t.Errorf("body = %v, want code %q", body, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestTaskEventsEndOnShutdown(t *testing.T) {
+	// без воркеров задача остаётся в очереди и поток сам не завершится
+	m := newTestManager(t, 0, manager.Config{})
+	mux := newTestMux(m)
+	stop := make(chan struct{})
+	mux.HandleFunc("GET /tasks/{id}/events", NewTaskEventsHandler(m, stop))
+	id := createTask(t, mux, `{"urls":["https://example.com/a.bin"]}`)
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	srv.Config.RegisterOnShutdown(func() { close(stop) })
+
+	resp, err := http.Get(srv.URL + "/tasks/" + id + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	// первое событие — текущее состояние задачи
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "event: status\n" {
+		t.Fatalf("first line %q, %v; want status event", line, err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if err := srv.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v; open event stream blocked it", err)
+	}
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Errorf("reading stream after shutdown: %v", err)
+	}
+}
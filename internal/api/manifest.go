@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"hh03012025/internal/manager"
+)
+
+// manifestType возвращает тип тела запроса на создание задачи, если это
+// манифест: "text/plain" или "text/csv". Для JSON возвращает пустую строку.
+func manifestType(r *http.Request) string {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	switch mt {
+	case "text/plain", "text/csv":
+		return mt
+	}
+	return ""
+}
+
+// parseManifest читает список файлов из манифеста построчно, не загружая
+// тело целиком. В text/plain каждая строка — URL; в text/csv строка имеет
+// вид url[,sha256[,filename]], а первая строка с "url" в первом столбце
+// считается заголовком. Пустые строки и строки, начинающиеся с "#",
+// пропускаются.
+func parseManifest(r io.Reader, contentType string) ([]manager.FileSpec, error) {
+	if contentType == "text/csv" {
+		return parseCSVManifest(r)
+	}
+	var specs []manager.FileSpec
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		u := strings.TrimSpace(sc.Text())
+		if u == "" || strings.HasPrefix(u, "#") {
+			continue
+		}
+		if strings.ContainsAny(u, " \t") {
+			return nil, fmt.Errorf("manifest line %d: expected a single URL", line)
+		}
+		specs = append(specs, manager.FileSpec{URL: u})
+	}
+	return specs, sc.Err()
+}
+
+// parseCSVManifest разбирает манифест text/csv (см. parseManifest).
+func parseCSVManifest(r io.Reader) ([]manager.FileSpec, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+	var specs []manager.FileSpec
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return specs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range rec {
+			rec[i] = strings.TrimSpace(rec[i])
+		}
+		if first && strings.EqualFold(rec[0], "url") {
+			continue
+		}
+		if len(rec) > 3 {
+			line, _ := cr.FieldPos(0)
+			return nil, fmt.Errorf("manifest line %d: expected url,sha256,filename, got %d fields", line, len(rec))
+		}
+		if rec[0] == "" {
+			continue
+		}
+		s := manager.FileSpec{URL: rec[0]}
+		if len(rec) > 1 {
+			s.SHA256 = rec[1]
+		}
+		if len(rec) > 2 {
+			s.Filename = rec[2]
+		}
+		specs = append(specs, s)
+	}
+}
+
+// manifestTaskSpec строит описание задачи из файлов манифеста и параметров
+// строки запроса: priority, callback_url, file_callback_url, dest_subdir,
+// filename_template, extract и max_concurrent — с тем же смыслом, что и
+// одноимённые поля JSON.
+func manifestTaskSpec(r *http.Request, files []manager.FileSpec) (manager.TaskSpec, error) {
+	q := r.URL.Query()
+	spec := manager.TaskSpec{
+		Files:            files,
+		CallbackURL:      strings.TrimSpace(q.Get("callback_url")),
+		FileCallbackURL:  strings.TrimSpace(q.Get("file_callback_url")),
+		FilenameTemplate: q.Get("filename_template"),
+		Priority:         strings.TrimSpace(q.Get("priority")),
+		DestSubdir:       q.Get("dest_subdir"),
+	}
+	var err error
+	if spec.Extract, err = queryBool(r, "extract"); err != nil {
+		return spec, err
+	}
+	if v := q.Get("max_concurrent"); v != "" {
+		if spec.MaxConcurrent, err = strconv.Atoi(v); err != nil {
+			return spec, fmt.Errorf("invalid max_concurrent %q", v)
+		}
+	}
+	return spec, nil
+}
@@ -0,0 +1,194 @@
+// Package archive распаковывает скачанные архивы zip и tar (в том числе
+// сжатые gzip) с защитой от выхода за пределы целевого каталога (zip-slip).
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsafePath возвращается, если путь элемента архива абсолютный или
+// выходит за пределы каталога распаковки.
+var ErrUnsafePath = errors.New("archive entry escapes target directory")
+
+// extensions — распознаваемые расширения архивов; более длинные раньше,
+// чтобы ".tar.gz" не было принято за ".gz".
+var extensions = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// Base возвращает имя без расширения архива и true, если name — архив
+// известного формата. Регистр расширения не учитывается.
+func Base(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	for _, ext := range extensions {
+		if strings.HasSuffix(lower, ext) && len(name) > len(ext) {
+			return name[:len(name)-len(ext)], true
+		}
+	}
+	return "", false
+}
+
+// Extract распаковывает архив src в каталог dir. Формат определяется по
+// расширению src. Распаковка идёт во временный каталог dir+".part", который
+// затем переименовывается в dir, поэтому при ошибке dir не остаётся
+// заполненным наполовину; прежнее содержимое dir заменяется. Символьные
+// ссылки и специальные файлы пропускаются. Распаковка прерывается при отмене
+// ctx между элементами архива.
+func Extract(ctx context.Context, src, dir string) error {
+	if _, ok := Base(filepath.Base(src)); !ok {
+		return fmt.Errorf("unsupported archive format: %s", filepath.Base(src))
+	}
+	tmp := dir + ".part"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tmp, 0o755); err != nil {
+		return err
+	}
+	var err error
+	if strings.HasSuffix(strings.ToLower(src), ".zip") {
+		err = extractZip(ctx, src, tmp)
+	} else {
+		err = extractTar(ctx, src, tmp)
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	return os.Rename(tmp, dir)
+}
+
+// extractZip распаковывает zip-архив src в dir.
+func extractZip(ctx context.Context, src, dir string) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		mode := f.Mode()
+		if !mode.IsDir() && !mode.IsRegular() {
+			continue
+		}
+		target, err := entryPath(dir, f.Name)
+		if err != nil {
+			return err
+		}
+		if mode.IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		err = writeFile(target, rc, mode)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// gzipMagic — первые байты потока gzip.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// extractTar распаковывает tar-архив src в dir. Сжатие gzip определяется по
+// сигнатуре, а не по расширению: файл .tar.gz мог быть уже распакован при
+// скачивании, если сервер отдал его с Content-Encoding: gzip.
+func extractTar(ctx context.Context, src, dir string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	tr := tar.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeDir && hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		target, err := entryPath(dir, hdr.Name)
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeFile(target, tr, hdr.FileInfo().Mode()); err != nil {
+			return fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+	}
+}
+
+// entryPath возвращает путь элемента name внутри dir, отклоняя абсолютные
+// пути и пути с "..", выходящие за пределы dir.
+func entryPath(dir, name string) (string, error) {
+	rel := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	return filepath.Join(dir, rel), nil
+}
+
+// writeFile записывает содержимое r в новый файл path. Из прав исходного
+// файла сохраняется только признак исполняемости.
+func writeFile(path string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	perm := os.FileMode(0o644)
+	if mode&0o111 != 0 {
+		perm = 0o755
+	}
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,704 @@
+// Package config собирает настройки сервиса из значений по умолчанию,
+// переменных окружения и флагов командной строки. Приоритет: флаги
+// переопределяют окружение, окружение — значения по умолчанию.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"hh03012025/internal/api"
+	"hh03012025/internal/download"
+	"hh03012025/internal/manager"
+	"hh03012025/internal/util"
+)
+
+// Config содержит все настраиваемые параметры сервиса.
+type Config struct {
+	Addr              string        // адрес HTTP‑сервера (LISTEN_ADDR, -addr)
+	GRPCAddr          string        // адрес gRPC-сервера, пусто — выключен (GRPC_ADDR, -grpc-addr)
+	DownloadDir       string        // каталог для файлов (DOWNLOAD_DIR, -download-dir)
+	SnapshotFile      string        // файл снапшота (SNAPSHOT_FILE, -snapshot-file)
+	SnapshotGzip      bool          // сжимать снапшот gzip (SNAPSHOT_GZIP, -snapshot-gzip)
+	SnapshotInterval  time.Duration // период записи снапшота, 0 — только при остановке (SNAPSHOT_INTERVAL, -snapshot-interval)
+	Store             string        // хранилище: json или sqlite (STORE, -store)
+	SQLitePath        string        // путь к базе SQLite (SQLITE_PATH, -sqlite-path)
+	Workers           int           // число воркеров (WORKERS, -workers)
+	QueueSize         int           // ёмкость очереди заданий (QUEUE_SIZE, -queue-size)
+	PerTaskLimit      int           // максимум параллельных файлов задачи, 0 — без лимита (MAX_CONCURRENT_PER_TASK, -max-concurrent-per-task)
+	DownloadTimeout   time.Duration // таймаут одного файла (DOWNLOAD_TIMEOUT, -download-timeout)
+	IdleTimeout       time.Duration // таймаут простоя (IDLE_TIMEOUT, -idle-timeout)
+	DownloadRetries   int           // повторов после временной ошибки, 0 — без повторов (DOWNLOAD_RETRIES, -download-retries)
+	RetryBackoff      time.Duration // пауза перед первым повтором, далее вдвое дольше (RETRY_BACKOFF, -retry-backoff)
+	RestoreJitter     time.Duration // окно случайной постановки в очередь файлов из снапшота (RESTORE_JITTER, -restore-jitter)
+	RetryStatuses     []string      // коды ответа для повтора через запятую, 5xx — класс (RETRYABLE_STATUSES, -retryable-statuses)
+	MaxFileSize       int64         // лимит размера файла, 0 — без лимита (MAX_FILE_SIZE, -max-file-size)
+	DownloadChunks    int           // частей при параллельном скачивании, 0 или 1 — одним потоком (DOWNLOAD_CHUNKS, -download-chunks)
+	ChunkMinSize      int64         // минимальный размер файла для скачивания частями (CHUNK_MIN_SIZE, -chunk-min-size)
+	ResumeDownloads   bool          // продолжать прерванные скачивания по .part (RESUME_DOWNLOADS, -resume-downloads)
+	CheckDiskSpace    bool          // проверять свободное место (CHECK_DISK_SPACE, -check-disk-space)
+	MinFreeDisk       int64         // запас свободного места в байтах (MIN_FREE_DISK, -min-free-disk)
+	MaxRedirects      int           // лимит редиректов, <0 — запрещены (MAX_REDIRECTS, -max-redirects)
+	SameHostRedirects bool          // редиректы только на исходный хост (SAME_HOST_REDIRECTS, -same-host-redirects)
+	AllowedHosts      []string      // разрешённые хосты через запятую (ALLOWED_HOSTS, -allowed-hosts)
+	BlockedHosts      []string      // запрещённые хосты через запятую (BLOCKED_HOSTS, -blocked-hosts)
+	BlockedHostMode   string        // реакция на запрещённый хост: file или task (BLOCKED_HOST_MODE, -blocked-host-mode)
+	AllowPrivateIPs   bool          // разрешить приватные и loopback адреса (ALLOW_PRIVATE_IPS, -allow-private-ips)
+	Proxy             string        // URL прокси http, https или socks5, пусто — из окружения (PROXY_URL, -proxy)
+	NoProxy           []string      // хосты без прокси через запятую (NO_PROXY, -no-proxy)
+	MaxIdleConns      int           // простаивающих соединений всего (MAX_IDLE_CONNS, -max-idle-conns)
+	IdleConnsPerHost  int           // простаивающих соединений на хост (MAX_IDLE_CONNS_PER_HOST, -max-idle-conns-per-host)
+	MaxConnsPerHost   int           // соединений на хост, 0 — без лимита (MAX_CONNS_PER_HOST, -max-conns-per-host)
+	IdleConnTimeout   time.Duration // закрывать простаивающие соединения через (IDLE_CONN_TIMEOUT, -idle-conn-timeout)
+	ConnectTimeout    time.Duration // таймаут установки соединения с источником (CONNECT_TIMEOUT, -connect-timeout)
+	MaxConnections    int           // максимум одновременных запросов к источникам, 0 — без лимита (MAX_CONNECTIONS, -max-connections)
+	TLSCAFile         string        // PEM-файл с дополнительными корневыми сертификатами (TLS_CA_FILE, -tls-ca-file)
+	TLSInsecure       bool          // не проверять сертификаты источников, опасно (TLS_INSECURE_SKIP_VERIFY, -tls-insecure-skip-verify)
+	HeadPreflight     bool          // HEAD-запрос перед скачиванием (HEAD_PREFLIGHT, -head-preflight)
+	S3Endpoint        string        // адрес S3-совместимого хранилища, пусто — AWS (S3_ENDPOINT, -s3-endpoint)
+	S3PathStyle       bool          // адресация endpoint/bucket/key для S3 (S3_PATH_STYLE, -s3-path-style)
+	UserAgent         string        // User-Agent запросов к источникам (USER_AGENT, -user-agent)
+	DefaultHeaders    http.Header   // заголовки всех запросов, JSON-объект (DEFAULT_HEADERS, -default-headers)
+	SecretQueryKeys   []string      // параметры запроса, скрываемые в логах и снапшоте (SENSITIVE_QUERY_KEYS, -sensitive-query-keys)
+	KeepEncoding      bool          // не распаковывать gzip/deflate ответы (KEEP_CONTENT_ENCODING, -keep-content-encoding)
+	StrictContentType bool          // отклонять ответ без Content-Type при ожидаемом типе (REQUIRE_CONTENT_TYPE, -require-content-type)
+	RejectHTMLPages   bool          // отклонять HTML вместо ожидаемого файла (REJECT_HTML_PAGES, -reject-html-pages)
+	IdempotencyTTL    time.Duration // срок жизни ключа идемпотентности (IDEMPOTENCY_TTL, -idempotency-ttl)
+	KeepDuplicates    bool          // не удалять повторяющиеся URL в задаче (KEEP_DUPLICATE_URLS, -keep-duplicate-urls)
+	DedupTasks        bool          // возвращать незавершённую задачу с тем же набором URL (DEDUP_TASKS, -dedup-tasks)
+	TaskIDPrefix      string        // префикс ID новых задач, например task_ (TASK_ID_PREFIX, -task-id-prefix)
+	TaskIDFormat      string        // формат ID новых задач: hex или uuid (TASK_ID_FORMAT, -task-id-format)
+	NameTemplate      string        // шаблон имён файлов, text/template (FILENAME_TEMPLATE, -filename-template)
+	OutputLayout      string        // раскладка файлов: per-task или flat-prefixed (OUTPUT_LAYOUT, -output-layout)
+	RunPastStartAt    bool          // сразу запускать задачи с прошедшим start_at (RUN_PAST_START_AT, -run-past-start-at)
+	MaxRequestBody    int64         // лимит тела запроса на создание задачи (MAX_REQUEST_BODY, -max-request-body)
+	MaxPollWait       time.Duration // максимальное ожидание изменений в GET /tasks/{id}?wait= (MAX_POLL_WAIT, -max-poll-wait)
+	MaxURLsPerTask    int           // максимум URL в задаче, 0 — без лимита (MAX_URLS_PER_TASK, -max-urls-per-task)
+	MaxTasks          int           // максимум задач в памяти, 0 — без лимита (MAX_TASKS, -max-tasks)
+	MaxActiveTasks    int           // максимум незавершённых задач, сверх — 429, 0 — без лимита (MAX_ACTIVE_TASKS, -max-active-tasks)
+	MaxDiskUsage      int64         // лимит суммарного размера скачанных файлов, 0 — без лимита (MAX_DISK_USAGE, -max-disk-usage)
+	DeleteEvicted     bool          // удалять файлы вытесненных задач (DELETE_EVICTED_FILES, -delete-evicted-files)
+	DeleteArchives    bool          // удалять архивы после распаковки (DELETE_EXTRACTED_ARCHIVES, -delete-extracted-archives)
+	FailOnSnapshot    bool          // не запускаться, если снапшот не прочитан (FAIL_ON_SNAPSHOT_ERROR, -fail-on-snapshot-error)
+	CleanStaleParts   bool          // удалять оставшиеся после сбоя .part при запуске (CLEAN_STALE_PARTS, -clean-stale-parts)
+	TaskTTL           time.Duration // срок хранения завершённых задач, 0 — бессрочно (TASK_TTL, -task-ttl)
+	ReapInterval      time.Duration // период поиска устаревших задач (REAP_INTERVAL, -reap-interval)
+	APIKeys           []string      // ключи доступа к API через запятую (API_KEYS, -api-keys)
+	CORSOrigins       []string      // разрешённые источники CORS (CORS_ORIGINS, -cors-origins)
+	CORSMethods       []string      // разрешённые методы CORS (CORS_METHODS, -cors-methods)
+	CORSHeaders       []string      // разрешённые заголовки CORS (CORS_HEADERS, -cors-headers)
+	CORSCredentials   bool          // разрешить запросы с учётными данными (CORS_CREDENTIALS, -cors-credentials)
+	ShutdownTimeout   time.Duration // ожидание запросов и активных загрузок при остановке (SHUTDOWN_TIMEOUT, -shutdown-timeout)
+	LogLevel          slog.Level    // уровень логирования (LOG_LEVEL, -log-level)
+	LogFormat         string        // формат логов: text или json (LOG_FORMAT, -log-format)
+}
+
+// Default возвращает конфигурацию по умолчанию.
+func Default() Config {
+	cors := api.DefaultCORSConfig()
+	return Config{
+		Addr:             ":8080",
+		DownloadDir:      "downloads",
+		SnapshotFile:     "tasks_snapshot.json",
+		SnapshotInterval: 15 * time.Second,
+		Store:            "json",
+		SQLitePath:       "tasks.db",
+		Workers:          5,
+		QueueSize:        100,
+		DownloadTimeout:  manager.DefaultDownloadTimeout,
+		IdleTimeout:      manager.DefaultIdleTimeout,
+		RetryBackoff:     manager.DefaultRetryBackoff,
+		RetryStatuses:    []string{"408", "429", "5xx"},
+		ChunkMinSize:     download.DefaultChunkMinSize,
+		MaxRedirects:     download.DefaultMaxRedirects,
+		UserAgent:        download.DefaultUserAgent,
+		MaxIdleConns:     100,
+		IdleConnsPerHost: 10,
+		IdleConnTimeout:  90 * time.Second,
+		ConnectTimeout:   download.DefaultConnectTimeout,
+		MaxConnections:   512,
+		BlockedHostMode:  "file",
+		IdempotencyTTL:   manager.DefaultIdempotencyTTL,
+		MaxRequestBody:   1 << 20,
+		MaxPollWait:      time.Minute,
+		MaxURLsPerTask:   1000,
+		ReapInterval:     time.Minute,
+		TaskIDFormat:     util.IDFormatHex,
+		OutputLayout:     manager.LayoutPerTask,
+		CleanStaleParts:  true,
+		CORSOrigins:      cors.AllowedOrigins,
+		CORSMethods:      cors.AllowedMethods,
+		CORSHeaders:      cors.AllowedHeaders,
+		ShutdownTimeout:  30 * time.Second,
+		LogLevel:         slog.LevelInfo,
+		LogFormat:        "text",
+	}
+}
+
+// Load строит конфигурацию из окружения и аргументов командной строки args
+// (без имени программы) и проверяет её. Ошибка содержит описание всех
+// некорректных значений.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	env := envReader{}
+	cfg.Addr = env.str("LISTEN_ADDR", cfg.Addr)
+	cfg.GRPCAddr = env.str("GRPC_ADDR", cfg.GRPCAddr)
+	cfg.DownloadDir = env.str("DOWNLOAD_DIR", cfg.DownloadDir)
+	cfg.SnapshotFile = env.str("SNAPSHOT_FILE", cfg.SnapshotFile)
+	cfg.SnapshotGzip = env.bool("SNAPSHOT_GZIP", cfg.SnapshotGzip)
+	cfg.SnapshotInterval = env.duration("SNAPSHOT_INTERVAL", cfg.SnapshotInterval)
+	cfg.Store = env.str("STORE", cfg.Store)
+	cfg.SQLitePath = env.str("SQLITE_PATH", cfg.SQLitePath)
+	cfg.Workers = env.int("WORKERS", cfg.Workers)
+	cfg.QueueSize = env.int("QUEUE_SIZE", cfg.QueueSize)
+	cfg.PerTaskLimit = env.int("MAX_CONCURRENT_PER_TASK", cfg.PerTaskLimit)
+	cfg.DownloadTimeout = env.duration("DOWNLOAD_TIMEOUT", cfg.DownloadTimeout)
+	cfg.IdleTimeout = env.duration("IDLE_TIMEOUT", cfg.IdleTimeout)
+	cfg.DownloadRetries = env.int("DOWNLOAD_RETRIES", cfg.DownloadRetries)
+	cfg.RetryBackoff = env.duration("RETRY_BACKOFF", cfg.RetryBackoff)
+	cfg.RestoreJitter = env.duration("RESTORE_JITTER", cfg.RestoreJitter)
+	cfg.RetryStatuses = env.list("RETRYABLE_STATUSES", cfg.RetryStatuses)
+	cfg.MaxFileSize = env.int64("MAX_FILE_SIZE", cfg.MaxFileSize)
+	cfg.DownloadChunks = env.int("DOWNLOAD_CHUNKS", cfg.DownloadChunks)
+	cfg.ResumeDownloads = env.bool("RESUME_DOWNLOADS", cfg.ResumeDownloads)
+	cfg.ChunkMinSize = env.int64("CHUNK_MIN_SIZE", cfg.ChunkMinSize)
+	cfg.CheckDiskSpace = env.bool("CHECK_DISK_SPACE", cfg.CheckDiskSpace)
+	cfg.MinFreeDisk = env.int64("MIN_FREE_DISK", cfg.MinFreeDisk)
+	cfg.MaxRedirects = env.int("MAX_REDIRECTS", cfg.MaxRedirects)
+	cfg.SameHostRedirects = env.bool("SAME_HOST_REDIRECTS", cfg.SameHostRedirects)
+	cfg.AllowedHosts = env.list("ALLOWED_HOSTS", cfg.AllowedHosts)
+	cfg.BlockedHosts = env.list("BLOCKED_HOSTS", cfg.BlockedHosts)
+	cfg.BlockedHostMode = env.str("BLOCKED_HOST_MODE", cfg.BlockedHostMode)
+	cfg.AllowPrivateIPs = env.bool("ALLOW_PRIVATE_IPS", cfg.AllowPrivateIPs)
+	cfg.Proxy = env.str("PROXY_URL", cfg.Proxy)
+	cfg.NoProxy = env.list("NO_PROXY", cfg.NoProxy)
+	cfg.MaxIdleConns = env.int("MAX_IDLE_CONNS", cfg.MaxIdleConns)
+	cfg.IdleConnsPerHost = env.int("MAX_IDLE_CONNS_PER_HOST", cfg.IdleConnsPerHost)
+	cfg.MaxConnsPerHost = env.int("MAX_CONNS_PER_HOST", cfg.MaxConnsPerHost)
+	cfg.IdleConnTimeout = env.duration("IDLE_CONN_TIMEOUT", cfg.IdleConnTimeout)
+	cfg.ConnectTimeout = env.duration("CONNECT_TIMEOUT", cfg.ConnectTimeout)
+	cfg.MaxConnections = env.int("MAX_CONNECTIONS", cfg.MaxConnections)
+	cfg.TLSCAFile = env.str("TLS_CA_FILE", cfg.TLSCAFile)
+	cfg.TLSInsecure = env.bool("TLS_INSECURE_SKIP_VERIFY", cfg.TLSInsecure)
+	cfg.HeadPreflight = env.bool("HEAD_PREFLIGHT", cfg.HeadPreflight)
+	cfg.S3Endpoint = env.str("S3_ENDPOINT", cfg.S3Endpoint)
+	cfg.S3PathStyle = env.bool("S3_PATH_STYLE", cfg.S3PathStyle)
+	cfg.UserAgent = env.str("USER_AGENT", cfg.UserAgent)
+	cfg.DefaultHeaders = env.headers("DEFAULT_HEADERS", cfg.DefaultHeaders)
+	cfg.SecretQueryKeys = env.list("SENSITIVE_QUERY_KEYS", cfg.SecretQueryKeys)
+	cfg.KeepEncoding = env.bool("KEEP_CONTENT_ENCODING", cfg.KeepEncoding)
+	cfg.StrictContentType = env.bool("REQUIRE_CONTENT_TYPE", cfg.StrictContentType)
+	cfg.RejectHTMLPages = env.bool("REJECT_HTML_PAGES", cfg.RejectHTMLPages)
+	cfg.IdempotencyTTL = env.duration("IDEMPOTENCY_TTL", cfg.IdempotencyTTL)
+	cfg.KeepDuplicates = env.bool("KEEP_DUPLICATE_URLS", cfg.KeepDuplicates)
+	cfg.DedupTasks = env.bool("DEDUP_TASKS", cfg.DedupTasks)
+	cfg.TaskIDPrefix = env.str("TASK_ID_PREFIX", cfg.TaskIDPrefix)
+	cfg.TaskIDFormat = env.str("TASK_ID_FORMAT", cfg.TaskIDFormat)
+	cfg.OutputLayout = env.str("OUTPUT_LAYOUT", cfg.OutputLayout)
+	cfg.NameTemplate = env.str("FILENAME_TEMPLATE", cfg.NameTemplate)
+	cfg.RunPastStartAt = env.bool("RUN_PAST_START_AT", cfg.RunPastStartAt)
+	cfg.MaxRequestBody = env.int64("MAX_REQUEST_BODY", cfg.MaxRequestBody)
+	cfg.MaxPollWait = env.duration("MAX_POLL_WAIT", cfg.MaxPollWait)
+	cfg.MaxURLsPerTask = env.int("MAX_URLS_PER_TASK", cfg.MaxURLsPerTask)
+	cfg.MaxTasks = env.int("MAX_TASKS", cfg.MaxTasks)
+	cfg.MaxActiveTasks = env.int("MAX_ACTIVE_TASKS", cfg.MaxActiveTasks)
+	cfg.MaxDiskUsage = env.int64("MAX_DISK_USAGE", cfg.MaxDiskUsage)
+	cfg.DeleteEvicted = env.bool("DELETE_EVICTED_FILES", cfg.DeleteEvicted)
+	cfg.DeleteArchives = env.bool("DELETE_EXTRACTED_ARCHIVES", cfg.DeleteArchives)
+	cfg.CleanStaleParts = env.bool("CLEAN_STALE_PARTS", cfg.CleanStaleParts)
+	cfg.FailOnSnapshot = env.bool("FAIL_ON_SNAPSHOT_ERROR", cfg.FailOnSnapshot)
+	cfg.TaskTTL = env.duration("TASK_TTL", cfg.TaskTTL)
+	cfg.ReapInterval = env.duration("REAP_INTERVAL", cfg.ReapInterval)
+	cfg.APIKeys = env.list("API_KEYS", cfg.APIKeys)
+	cfg.CORSOrigins = env.list("CORS_ORIGINS", cfg.CORSOrigins)
+	cfg.CORSMethods = env.list("CORS_METHODS", cfg.CORSMethods)
+	cfg.CORSHeaders = env.list("CORS_HEADERS", cfg.CORSHeaders)
+	cfg.CORSCredentials = env.bool("CORS_CREDENTIALS", cfg.CORSCredentials)
+	cfg.ShutdownTimeout = env.duration("SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout)
+	cfg.LogLevel = env.level("LOG_LEVEL", cfg.LogLevel)
+	cfg.LogFormat = env.str("LOG_FORMAT", cfg.LogFormat)
+	if err := errors.Join(env.errs...); err != nil {
+		return cfg, err
+	}
+
+	fs := flag.NewFlagSet("hh03012025", flag.ContinueOnError)
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "адрес HTTP-сервера")
+	fs.StringVar(&cfg.GRPCAddr, "grpc-addr", cfg.GRPCAddr, "адрес gRPC-сервера, пусто — gRPC выключен")
+	fs.StringVar(&cfg.DownloadDir, "download-dir", cfg.DownloadDir, "каталог для скачанных файлов")
+	fs.StringVar(&cfg.SnapshotFile, "snapshot-file", cfg.SnapshotFile, "путь к файлу снапшота")
+	fs.BoolVar(&cfg.SnapshotGzip, "snapshot-gzip", cfg.SnapshotGzip, "сжимать снапшот gzip (файл с расширением .gz)")
+	fs.DurationVar(&cfg.SnapshotInterval, "snapshot-interval", cfg.SnapshotInterval, "период записи снапшота, 0 — только при остановке")
+	fs.StringVar(&cfg.Store, "store", cfg.Store, "хранилище состояния: json или sqlite")
+	fs.StringVar(&cfg.SQLitePath, "sqlite-path", cfg.SQLitePath, "путь к базе SQLite")
+	fs.IntVar(&cfg.Workers, "workers", cfg.Workers, "число воркеров")
+	fs.IntVar(&cfg.QueueSize, "queue-size", cfg.QueueSize, "ёмкость очереди заданий")
+	fs.IntVar(&cfg.PerTaskLimit, "max-concurrent-per-task", cfg.PerTaskLimit, "максимум одновременно скачиваемых файлов одной задачи (0 — без ограничения)")
+	fs.DurationVar(&cfg.DownloadTimeout, "download-timeout", cfg.DownloadTimeout, "таймаут скачивания одного файла")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "таймаут простоя (0 — отключён)")
+	fs.IntVar(&cfg.DownloadRetries, "download-retries", cfg.DownloadRetries, "сколько раз повторить скачивание после временной ошибки (0 — без повторов)")
+	fs.DurationVar(&cfg.RetryBackoff, "retry-backoff", cfg.RetryBackoff, "пауза перед первым повтором, каждая следующая вдвое длиннее")
+	fs.DurationVar(&cfg.RestoreJitter, "restore-jitter", cfg.RestoreJitter, "окно, по которому случайно распределяется постановка в очередь файлов из снапшота после перезапуска (0 — сразу)")
+	fs.Func("retryable-statuses", "коды ответа, после которых скачивание повторяется, через запятую (5xx — весь класс)", listFlag(&cfg.RetryStatuses))
+	fs.Int64Var(&cfg.MaxFileSize, "max-file-size", cfg.MaxFileSize, "максимальный размер файла в байтах (0 — без ограничения)")
+	fs.BoolVar(&cfg.ResumeDownloads, "resume-downloads", cfg.ResumeDownloads, "продолжать прерванные HTTP-скачивания с места остановки")
+	fs.IntVar(&cfg.DownloadChunks, "download-chunks", cfg.DownloadChunks, "число параллельных частей при скачивании больших файлов (0 или 1 — одним потоком)")
+	fs.Int64Var(&cfg.ChunkMinSize, "chunk-min-size", cfg.ChunkMinSize, "минимальный размер файла в байтах для скачивания частями")
+	fs.BoolVar(&cfg.CheckDiskSpace, "check-disk-space", cfg.CheckDiskSpace, "проверять свободное место перед скачиванием")
+	fs.Int64Var(&cfg.MinFreeDisk, "min-free-disk", cfg.MinFreeDisk, "запас свободного места на диске в байтах")
+	fs.IntVar(&cfg.MaxRedirects, "max-redirects", cfg.MaxRedirects, "максимальное число редиректов (отрицательное — запретить)")
+	fs.BoolVar(&cfg.SameHostRedirects, "same-host-redirects", cfg.SameHostRedirects, "разрешать редиректы только на исходный хост")
+	fs.Func("allowed-hosts", "разрешённые хосты через запятую (.example.com — с поддоменами)", listFlag(&cfg.AllowedHosts))
+	fs.Func("blocked-hosts", "запрещённые хосты через запятую (.example.com — с поддоменами)", listFlag(&cfg.BlockedHosts))
+	fs.StringVar(&cfg.BlockedHostMode, "blocked-host-mode", cfg.BlockedHostMode, "реакция на запрещённый хост: file (ошибка файла) или task (отклонить задачу)")
+	fs.BoolVar(&cfg.AllowPrivateIPs, "allow-private-ips", cfg.AllowPrivateIPs, "разрешить скачивание с приватных и loopback адресов")
+	fs.StringVar(&cfg.Proxy, "proxy", cfg.Proxy, "URL прокси-сервера (http, https, socks5); пусто — HTTP_PROXY/HTTPS_PROXY")
+	fs.Func("no-proxy", "хосты, к которым подключаться без прокси, через запятую", listFlag(&cfg.NoProxy))
+	fs.IntVar(&cfg.MaxIdleConns, "max-idle-conns", cfg.MaxIdleConns, "максимум простаивающих соединений с источниками")
+	fs.IntVar(&cfg.IdleConnsPerHost, "max-idle-conns-per-host", cfg.IdleConnsPerHost, "максимум простаивающих соединений с одним хостом")
+	fs.IntVar(&cfg.MaxConnsPerHost, "max-conns-per-host", cfg.MaxConnsPerHost, "максимум соединений с одним хостом (0 — без ограничения)")
+	fs.DurationVar(&cfg.IdleConnTimeout, "idle-conn-timeout", cfg.IdleConnTimeout, "через сколько закрывать простаивающее соединение")
+	fs.DurationVar(&cfg.ConnectTimeout, "connect-timeout", cfg.ConnectTimeout, "сколько ждать установки соединения с источником")
+	fs.IntVar(&cfg.MaxConnections, "max-connections", cfg.MaxConnections, "максимум одновременных запросов к источникам во всех скачиваниях (0 — без ограничения)")
+	fs.StringVar(&cfg.TLSCAFile, "tls-ca-file", cfg.TLSCAFile, "PEM-файл с дополнительными корневыми сертификатами")
+	fs.BoolVar(&cfg.TLSInsecure, "tls-insecure-skip-verify", cfg.TLSInsecure, "не проверять TLS-сертификаты источников (небезопасно)")
+	fs.BoolVar(&cfg.HeadPreflight, "head-preflight", cfg.HeadPreflight, "выполнять HEAD-запрос перед скачиванием")
+	fs.StringVar(&cfg.S3Endpoint, "s3-endpoint", cfg.S3Endpoint, "адрес S3-совместимого хранилища для ссылок s3:// (пусто — AWS S3)")
+	fs.BoolVar(&cfg.S3PathStyle, "s3-path-style", cfg.S3PathStyle, "адресация endpoint/bucket/key для S3-совместимых хранилищ")
+	fs.StringVar(&cfg.UserAgent, "user-agent", cfg.UserAgent, "User-Agent запросов к источникам")
+	fs.Func("default-headers", `заголовки всех запросов в виде JSON-объекта, например {"Accept": "*/*"}`, headersFlag(&cfg.DefaultHeaders))
+	fs.Func("sensitive-query-keys", "параметры запроса через запятую, значения которых скрываются в логах и снапшоте (пусто — список по умолчанию)", listFlag(&cfg.SecretQueryKeys))
+	fs.BoolVar(&cfg.KeepEncoding, "keep-content-encoding", cfg.KeepEncoding, "сохранять сжатые gzip/deflate ответы без распаковки")
+	fs.BoolVar(&cfg.StrictContentType, "require-content-type", cfg.StrictContentType, "считать ошибкой ответ без Content-Type, если задан ожидаемый тип")
+	fs.BoolVar(&cfg.RejectHTMLPages, "reject-html-pages", cfg.RejectHTMLPages, "считать ошибкой HTML-страницу вместо файла другого типа")
+	fs.DurationVar(&cfg.IdempotencyTTL, "idempotency-ttl", cfg.IdempotencyTTL, "срок жизни ключа идемпотентности")
+	fs.BoolVar(&cfg.KeepDuplicates, "keep-duplicate-urls", cfg.KeepDuplicates, "не удалять повторяющиеся URL в задаче")
+	fs.BoolVar(&cfg.DedupTasks, "dedup-tasks", cfg.DedupTasks, "возвращать незавершённую задачу с тем же набором URL вместо создания новой")
+	fs.StringVar(&cfg.TaskIDPrefix, "task-id-prefix", cfg.TaskIDPrefix, "префикс ID новых задач, например task_")
+	fs.StringVar(&cfg.TaskIDFormat, "task-id-format", cfg.TaskIDFormat, "формат ID новых задач: hex или uuid")
+	fs.StringVar(&cfg.NameTemplate, "filename-template", cfg.NameTemplate, "шаблон имён файлов новых задач (text/template), например {{.TaskID}}-{{.Index}}{{.Ext}}; пусто — имя из URL")
+	fs.StringVar(&cfg.OutputLayout, "output-layout", cfg.OutputLayout, "раскладка файлов новых задач: per-task (каталог {id}) или flat-prefixed (файлы {id}_{имя} в общем каталоге)")
+	fs.BoolVar(&cfg.RunPastStartAt, "run-past-start-at", cfg.RunPastStartAt, "сразу запускать задачи с прошедшим start_at вместо ответа 400")
+	fs.Int64Var(&cfg.MaxRequestBody, "max-request-body", cfg.MaxRequestBody, "максимальный размер тела запроса на создание задачи в байтах")
+	fs.DurationVar(&cfg.MaxPollWait, "max-poll-wait", cfg.MaxPollWait, "максимальное время ожидания изменений задачи в GET /tasks/{id}?wait=")
+	fs.IntVar(&cfg.MaxURLsPerTask, "max-urls-per-task", cfg.MaxURLsPerTask, "максимальное число URL в задаче (0 — без ограничения)")
+	fs.IntVar(&cfg.MaxTasks, "max-tasks", cfg.MaxTasks, "максимальное число задач в памяти (0 — без ограничения)")
+	fs.IntVar(&cfg.MaxActiveTasks, "max-active-tasks", cfg.MaxActiveTasks, "максимальное число незавершённых задач, новые сверх лимита отклоняются с 429 (0 — без ограничения)")
+	fs.Int64Var(&cfg.MaxDiskUsage, "max-disk-usage", cfg.MaxDiskUsage, "лимит суммарного размера скачанных файлов в байтах, сверх него вытесняются завершённые задачи (0 — без ограничения)")
+	fs.BoolVar(&cfg.DeleteEvicted, "delete-evicted-files", cfg.DeleteEvicted, "удалять файлы задач, вытесненных из памяти")
+	fs.BoolVar(&cfg.DeleteArchives, "delete-extracted-archives", cfg.DeleteArchives, "удалять архивы после успешной распаковки")
+	fs.BoolVar(&cfg.FailOnSnapshot, "fail-on-snapshot-error", cfg.FailOnSnapshot, "завершаться при запуске, если сохранённое состояние не удалось прочитать, вместо запуска без задач")
+	fs.BoolVar(&cfg.CleanStaleParts, "clean-stale-parts", cfg.CleanStaleParts, "удалять при запуске временные .part, оставшиеся после сбоя")
+	fs.DurationVar(&cfg.TaskTTL, "task-ttl", cfg.TaskTTL, "удалять завершённые задачи и их файлы спустя это время (0 — никогда)")
+	fs.DurationVar(&cfg.ReapInterval, "reap-interval", cfg.ReapInterval, "период поиска устаревших задач")
+	fs.Func("api-keys", "ключи доступа к API через запятую (пусто — без проверки)", listFlag(&cfg.APIKeys))
+	fs.Func("cors-origins", "разрешённые источники CORS через запятую (* — любой)", listFlag(&cfg.CORSOrigins))
+	fs.Func("cors-methods", "разрешённые методы CORS через запятую", listFlag(&cfg.CORSMethods))
+	fs.Func("cors-headers", "разрешённые заголовки CORS через запятую", listFlag(&cfg.CORSHeaders))
+	fs.BoolVar(&cfg.CORSCredentials, "cors-credentials", cfg.CORSCredentials, "разрешить CORS-запросы с учётными данными")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "сколько ждать завершения HTTP-запросов, gRPC-вызовов и загрузок при остановке")
+	fs.TextVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "уровень логирования: debug, info, warn, error")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "формат логов: text или json")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// Validate проверяет, что значения находятся в допустимых диапазонах.
+func (c Config) Validate() error {
+	var errs []error
+	if c.Addr == "" {
+		errs = append(errs, errors.New("listen address must not be empty"))
+	}
+	if c.DownloadDir == "" {
+		errs = append(errs, errors.New("download dir must not be empty"))
+	}
+	switch c.Store {
+	case "json":
+		if c.SnapshotFile == "" {
+			errs = append(errs, errors.New("snapshot file must not be empty"))
+		}
+	case "sqlite":
+		if c.SQLitePath == "" {
+			errs = append(errs, errors.New("sqlite path must not be empty"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("store must be json or sqlite, got %q", c.Store))
+	}
+	if c.Workers <= 0 {
+		errs = append(errs, fmt.Errorf("workers must be positive, got %d", c.Workers))
+	}
+	if c.QueueSize <= 0 {
+		errs = append(errs, fmt.Errorf("queue size must be positive, got %d", c.QueueSize))
+	}
+	if c.DownloadTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("download timeout must be positive, got %s", c.DownloadTimeout))
+	}
+	if c.IdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("idle timeout must not be negative, got %s", c.IdleTimeout))
+	}
+	if c.DownloadRetries < 0 {
+		errs = append(errs, fmt.Errorf("download retries must not be negative, got %d", c.DownloadRetries))
+	}
+	if c.RetryBackoff <= 0 {
+		errs = append(errs, fmt.Errorf("retry backoff must be positive, got %s", c.RetryBackoff))
+	}
+	if c.RestoreJitter < 0 {
+		errs = append(errs, fmt.Errorf("restore jitter must not be negative, got %s", c.RestoreJitter))
+	}
+	if _, err := parseStatusCodes(c.RetryStatuses); err != nil {
+		errs = append(errs, err)
+	}
+	if c.MaxFileSize < 0 {
+		errs = append(errs, fmt.Errorf("max file size must not be negative, got %d", c.MaxFileSize))
+	}
+	if c.DownloadChunks < 0 {
+		errs = append(errs, fmt.Errorf("download chunks must not be negative, got %d", c.DownloadChunks))
+	}
+	if c.ChunkMinSize <= 0 {
+		errs = append(errs, fmt.Errorf("chunk min size must be positive, got %d", c.ChunkMinSize))
+	}
+	if c.MinFreeDisk < 0 {
+		errs = append(errs, fmt.Errorf("min free disk must not be negative, got %d", c.MinFreeDisk))
+	}
+	if c.BlockedHostMode != "file" && c.BlockedHostMode != "task" {
+		errs = append(errs, fmt.Errorf("blocked host mode must be file or task, got %q", c.BlockedHostMode))
+	}
+	if c.IdempotencyTTL <= 0 {
+		errs = append(errs, fmt.Errorf("idempotency TTL must be positive, got %s", c.IdempotencyTTL))
+	}
+	if c.MaxPollWait < 0 {
+		errs = append(errs, fmt.Errorf("max poll wait must not be negative, got %s", c.MaxPollWait))
+	}
+	if c.MaxRequestBody <= 0 {
+		errs = append(errs, fmt.Errorf("max request body must be positive, got %d", c.MaxRequestBody))
+	}
+	if c.Proxy != "" {
+		if err := download.ValidateProxy(c.Proxy); err != nil {
+			errs = append(errs, fmt.Errorf("invalid proxy: %w", err))
+		}
+	}
+	if c.MaxConnections < 0 {
+		errs = append(errs, fmt.Errorf("max connections must not be negative, got %d", c.MaxConnections))
+	}
+	if c.ConnectTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("connect timeout must be positive, got %s", c.ConnectTimeout))
+	}
+	if c.MaxIdleConns < 0 || c.IdleConnsPerHost < 0 || c.MaxConnsPerHost < 0 || c.IdleConnTimeout < 0 {
+		errs = append(errs, errors.New("connection pool settings must not be negative"))
+	}
+	if !util.ValidIDPrefix(c.TaskIDPrefix) {
+		errs = append(errs, fmt.Errorf("task ID prefix must be at most %d characters of a-z, 0-9, _ and -, got %q", util.MaxIDPrefix, c.TaskIDPrefix))
+	}
+	if c.TaskIDFormat != util.IDFormatHex && c.TaskIDFormat != util.IDFormatUUID {
+		errs = append(errs, fmt.Errorf("task ID format must be hex or uuid, got %q", c.TaskIDFormat))
+	}
+	if _, err := download.ParseNameTemplate(c.NameTemplate); err != nil {
+		errs = append(errs, fmt.Errorf("invalid filename template: %w", err))
+	}
+	if c.OutputLayout != manager.LayoutPerTask && c.OutputLayout != manager.LayoutFlatPrefixed {
+		errs = append(errs, fmt.Errorf("output layout must be %s or %s, got %q", manager.LayoutPerTask, manager.LayoutFlatPrefixed, c.OutputLayout))
+	}
+	if c.PerTaskLimit < 0 {
+		errs = append(errs, fmt.Errorf("max concurrent per task must not be negative, got %d", c.PerTaskLimit))
+	}
+	if c.MaxURLsPerTask < 0 {
+		errs = append(errs, fmt.Errorf("max URLs per task must not be negative, got %d", c.MaxURLsPerTask))
+	}
+	if c.MaxActiveTasks < 0 {
+		errs = append(errs, fmt.Errorf("max active tasks must not be negative, got %d", c.MaxActiveTasks))
+	}
+	if c.MaxDiskUsage < 0 {
+		errs = append(errs, fmt.Errorf("max disk usage must not be negative, got %d", c.MaxDiskUsage))
+	}
+	if c.MaxTasks < 0 {
+		errs = append(errs, fmt.Errorf("max tasks must not be negative, got %d", c.MaxTasks))
+	}
+	if c.TaskTTL < 0 {
+		errs = append(errs, fmt.Errorf("task TTL must not be negative, got %s", c.TaskTTL))
+	}
+	if c.SnapshotInterval < 0 {
+		errs = append(errs, fmt.Errorf("snapshot interval must be non-negative, got %s", c.SnapshotInterval))
+	}
+	if c.ReapInterval <= 0 {
+		errs = append(errs, fmt.Errorf("reap interval must be positive, got %s", c.ReapInterval))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("shutdown timeout must be positive, got %s", c.ShutdownTimeout))
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("log format must be text or json, got %q", c.LogFormat))
+	}
+	return errors.Join(errs...)
+}
+
+// Logger создаёт логгер с настроенными уровнем и форматом вывода в stderr.
+func (c Config) Logger() *slog.Logger {
+	opts := &slog.HandlerOptions{Level: c.LogLevel}
+	if c.LogFormat == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
+}
+
+// ManagerConfig возвращает параметры, относящиеся к менеджеру задач.
+// Сертификаты из TLSCAFile в RootCAs не загружаются: это делает вызывающий,
+// чтобы обработать ошибку чтения файла.
+func (c Config) ManagerConfig() manager.Config {
+	return manager.Config{
+		DownloadDir:       c.DownloadDir,
+		DownloadTimeout:   c.DownloadTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		DownloadRetries:   c.DownloadRetries,
+		RetryBackoff:      c.RetryBackoff,
+		RestoreJitter:     c.RestoreJitter,
+		RetryableStatuses: c.retryableStatuses(),
+		MaxFileSize:       c.MaxFileSize,
+		DownloadChunks:    c.DownloadChunks,
+		ResumeDownloads:   c.ResumeDownloads,
+		ChunkMinSize:      c.ChunkMinSize,
+		IdempotencyTTL:    c.IdempotencyTTL,
+		DedupTasks:        c.DedupTasks,
+		IDPrefix:          c.TaskIDPrefix,
+		IDFormat:          c.TaskIDFormat,
+		Layout:            c.OutputLayout,
+		FilenameTemplate:  c.nameTemplate(),
+		RunPastStartAt:    c.RunPastStartAt,
+		KeepDuplicateURLs: c.KeepDuplicates,
+		CheckDiskSpace:    c.CheckDiskSpace,
+		MinFreeDisk:       c.MinFreeDisk,
+		MaxRedirects:      c.MaxRedirects,
+		SameHostRedirects: c.SameHostRedirects,
+		HostPolicy: download.HostPolicy{
+			Allow: c.AllowedHosts,
+			Block: c.BlockedHosts,
+		},
+		RejectBlockedHosts:      c.BlockedHostMode == "task",
+		AllowPrivateIPs:         c.AllowPrivateIPs,
+		Proxy:                   c.Proxy,
+		NoProxy:                 c.NoProxy,
+		MaxIdleConns:            c.MaxIdleConns,
+		MaxIdleConnsPerHost:     c.IdleConnsPerHost,
+		MaxConnsPerHost:         c.MaxConnsPerHost,
+		IdleConnTimeout:         c.IdleConnTimeout,
+		ConnectTimeout:          c.ConnectTimeout,
+		MaxConnections:          c.MaxConnections,
+		TLSInsecureSkipVerify:   c.TLSInsecure,
+		HeadPreflight:           c.HeadPreflight,
+		S3Endpoint:              c.S3Endpoint,
+		S3PathStyle:             c.S3PathStyle,
+		UserAgent:               c.UserAgent,
+		DefaultHeaders:          c.DefaultHeaders,
+		SensitiveQueryKeys:      c.SecretQueryKeys,
+		KeepContentEncoding:     c.KeepEncoding,
+		RequireContentType:      c.StrictContentType,
+		RejectHTMLPages:         c.RejectHTMLPages,
+		MaxURLsPerTask:          c.MaxURLsPerTask,
+		MaxConcurrentPerTask:    c.PerTaskLimit,
+		MaxTasks:                c.MaxTasks,
+		MaxActiveTasks:          c.MaxActiveTasks,
+		MaxDiskUsage:            c.MaxDiskUsage,
+		DeleteEvictedFiles:      c.DeleteEvicted,
+		DeleteExtractedArchives: c.DeleteArchives,
+		TaskTTL:                 c.TaskTTL,
+	}
+}
+
+// CORSConfig возвращает политику CORS для HTTP-сервера.
+func (c Config) CORSConfig() api.CORSConfig {
+	return api.CORSConfig{
+		AllowedOrigins:   c.CORSOrigins,
+		AllowedMethods:   c.CORSMethods,
+		AllowedHeaders:   c.CORSHeaders,
+		AllowCredentials: c.CORSCredentials,
+	}
+}
+
+// envReader читает переменные окружения, накапливая ошибки разбора.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) str(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+func (e *envReader) int(key string, def int) int {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid integer %q", key, v))
+		return def
+	}
+	return n
+}
+
+func (e *envReader) int64(key string, def int64) int64 {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid integer %q", key, v))
+		return def
+	}
+	return n
+}
+
+func (e *envReader) duration(key string, def time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid duration %q", key, v))
+		return def
+	}
+	return d
+}
+
+func (e *envReader) bool(key string, def bool) bool {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid boolean %q", key, v))
+		return def
+	}
+	return b
+}
+
+func (e *envReader) level(key string, def slog.Level) slog.Level {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return def
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(v)); err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid log level %q", key, v))
+		return def
+	}
+	return l
+}
+
+func (e *envReader) list(key string, def []string) []string {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return def
+	}
+	return splitList(v)
+}
+
+func (e *envReader) headers(key string, def http.Header) http.Header {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return def
+	}
+	h, err := parseHeaders(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %w", key, err))
+		return def
+	}
+	return h
+}
+
+// headersFlag возвращает обработчик флага с заголовками в виде JSON-объекта.
+func headersFlag(dst *http.Header) func(string) error {
+	return func(v string) error {
+		h, err := parseHeaders(v)
+		if err != nil {
+			return err
+		}
+		*dst = h
+		return nil
+	}
+}
+
+// parseHeaders разбирает JSON-объект {"Имя": "значение"}. JSON выбран
+// потому, что значения заголовков сами могут содержать запятые и точки с
+// запятой.
+func parseHeaders(v string) (http.Header, error) {
+	var m map[string]string
+	if err := json.Unmarshal([]byte(v), &m); err != nil {
+		return nil, fmt.Errorf("invalid headers JSON object %q", v)
+	}
+	h := make(http.Header, len(m))
+	for k, val := range m {
+		h.Set(k, val)
+	}
+	return h, nil
+}
+
+// listFlag возвращает обработчик флага со списком значений через запятую.
+func listFlag(dst *[]string) func(string) error {
+	return func(v string) error {
+		*dst = splitList(v)
+		return nil
+	}
+}
+
+// retryableStatuses возвращает коды из RetryStatuses; их корректность
+// проверяет Validate.
+func (c Config) retryableStatuses() []int {
+	codes, _ := parseStatusCodes(c.RetryStatuses)
+	return codes
+}
+
+// nameTemplate возвращает разобранный NameTemplate; его корректность
+// проверяет Validate.
+func (c Config) nameTemplate() *download.NameTemplate {
+	t, _ := download.ParseNameTemplate(c.NameTemplate)
+	return t
+}
+
+// parseStatusCodes разбирает коды ответа HTTP: "503" — один код, "5xx" —
+// все коды класса. Пустой список — пустое множество, а не значения по
+// умолчанию.
+func parseStatusCodes(items []string) ([]int, error) {
+	codes := []int{}
+	for _, item := range items {
+		if len(item) == 3 && item[0] >= '1' && item[0] <= '5' && strings.EqualFold(item[1:], "xx") {
+			base := int(item[0]-'0') * 100
+			for c := base; c < base+100; c++ {
+				codes = append(codes, c)
+			}
+			continue
+		}
+		c, err := strconv.Atoi(item)
+		if err != nil || c < 100 || c > 599 {
+			return nil, fmt.Errorf("invalid retryable status %q: must be a code like 503 or a class like 5xx", item)
+		}
+		codes = append(codes, c)
+	}
+	return codes, nil
+}
+
+// splitList разбивает строку по запятым, отбрасывая пустые элементы.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
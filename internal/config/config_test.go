@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestLoadFailOnSnapshot(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		args    []string
+		want    bool
+		wantErr bool
+	}{
+		{"default", "", nil, false, false},
+		{"env", "true", nil, true, false},
+		{"flag", "", []string{"-fail-on-snapshot-error"}, true, false},
+		{"flag overrides env", "true", []string{"-fail-on-snapshot-error=false"}, false, false},
+		{"invalid env", "maybe", nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FAIL_ON_SNAPSHOT_ERROR", tt.env)
+			cfg, err := Load(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.FailOnSnapshot != tt.want {
+				t.Errorf("FailOnSnapshot = %v, want %v", cfg.FailOnSnapshot, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultChunkMinSize — минимальный размер файла для скачивания частями при
+// нулевом Options.ChunkMinSize. Меньшие файлы быстрее скачать одним потоком.
+const DefaultChunkMinSize = 16 << 20
+
+// errRangeIgnored возвращается, если сервер ответил на запрос диапазона не
+// 206 Partial Content: не поддерживает Range или файл изменился (If-Range).
+var errRangeIgnored = errors.New("server ignored range request")
+
+// byteRange — диапазон байт [start, end] включительно.
+type byteRange struct {
+	start, end int64
+}
+
+// splitRanges делит size байт на n почти равных диапазонов.
+func splitRanges(size int64, n int) []byteRange {
+	n = int(min(int64(n), size))
+	ranges := make([]byteRange, 0, n)
+	chunk := size / int64(n)
+	for i := range n {
+		start := int64(i) * chunk
+		end := start + chunk - 1
+		if i == n-1 {
+			end = size - 1
+		}
+		ranges = append(ranges, byteRange{start, end})
+	}
+	return ranges
+}
+
+// downloadChunked скачивает файл параллельно Options.Chunks частями: HEAD
+// сообщает размер и поддержку Range, затем каждая часть запрашивается
+// отдельным GET с заголовком Range и пишется в свой участок заранее
+// выделенного временного файла. Если сервер не поддерживает диапазоны, размер
+// неизвестен или меньше ChunkMinSize, возвращается ok=false без ошибки, и
+// файл скачивается одним потоком.
+func downloadChunked(ctx context.Context, fileURL, dest string, opts Options) (res Result, ok bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, fileURL, nil)
+	if err != nil {
+		return res, false, err
+	}
+	setRangeHeaders(req, opts)
+	release, err := opts.Conns.acquire(ctx)
+	if err != nil {
+		return res, true, err
+	}
+	resp, err := client(opts).Do(req)
+	if err != nil {
+		release()
+		// ошибку сети или политики хостов сообщит обычное скачивание
+		return res, false, ctx.Err()
+	}
+	resp.Body.Close()
+	// слот HEAD освобождается сразу: части занимают свои
+	release()
+	minSize := opts.ChunkMinSize
+	if minSize <= 0 {
+		minSize = DefaultChunkMinSize
+	}
+	size := resp.ContentLength
+	if resp.StatusCode != http.StatusOK || size < minSize ||
+		!strings.EqualFold(strings.TrimSpace(resp.Header.Get("Accept-Ranges")), "bytes") ||
+		!identityEncoding(resp.Header.Get("Content-Encoding")) {
+		return res, false, nil
+	}
+	res.FinalURL = resp.Request.URL.String()
+	res.StatusCode = resp.StatusCode
+	res.ContentType = resp.Header.Get("Content-Type")
+	res.ETag = resp.Header.Get("ETag")
+	res.LastModified = resp.Header.Get("Last-Modified")
+
+	if opts.OnResponse != nil {
+		opts.OnResponse(Info{Size: size, ContentType: res.ContentType})
+	}
+	if err := checkContentType(res.ContentType, opts.ExpectedContentType, opts.AllowMissingContentType); err != nil {
+		return res, true, err
+	}
+	if opts.MaxBytes > 0 && size > opts.MaxBytes {
+		return res, true, fmt.Errorf("%w: %d > %d bytes", ErrTooLarge, size, opts.MaxBytes)
+	}
+	if opts.Reserve != nil {
+		if err := opts.Reserve(size); err != nil {
+			return res, true, err
+		}
+	}
+	if opts.CheckDiskSpace {
+		if err := checkDiskSpace(filepath.Dir(dest), size, opts.MinFreeBytes); err != nil {
+			return res, true, err
+		}
+	}
+
+	// If-Range защищает от склейки частей разных версий файла: если файл
+	// изменился, сервер вернёт 200 вместо 206, и скачивание завершится ошибкой
+	validator := rangeValidator(res.ETag, res.LastModified)
+
+	tmp := dest + ".part"
+	tmpFile, err := os.Create(tmp)
+	if err != nil {
+		return res, true, err
+	}
+	// части пишутся вразнобой, поэтому недокачанный файл не продолжить:
+	// при любой ошибке он удаляется
+	defer func() {
+		tmpFile.Close()
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+	if err := tmpFile.Truncate(size); err != nil {
+		return res, true, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, r := range splitRanges(size, opts.Chunks) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fetchRange(ctx, fileURL, validator, tmpFile, r, opts); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return res, true, firstErr
+	}
+
+	// Каждая часть проверена по длине, но размер файла сверяем ещё раз
+	fi, err := tmpFile.Stat()
+	if err != nil {
+		return res, true, err
+	}
+	if fi.Size() != size {
+		return res, true, fmt.Errorf("chunked download: %w: got %d bytes, expected %d", ErrSizeMismatch, fi.Size(), size)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return res, true, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return res, true, err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return res, true, err
+	}
+	res.Size = size
+	return res, true, nil
+}
+
+// fetchRange скачивает диапазон r и записывает его в f по смещению r.start.
+// Первая часть проверяется на HTML-страницу при Options.RejectHTML.
+func fetchRange(ctx context.Context, fileURL, validator string, f *os.File, r byteRange, opts Options) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return err
+	}
+	setRangeHeaders(req, opts)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", r.start, r.end))
+	if validator != "" {
+		req.Header.Set("If-Range", validator)
+	}
+	release, err := opts.Conns.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	resp, err := client(opts).Do(req)
+	if err != nil {
+		return privateAddressError(redactError(err, opts))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("%w: %s", errRangeIgnored, resp.Status)
+	}
+	if want := fmt.Sprintf("bytes %d-%d/", r.start, r.end); !strings.HasPrefix(resp.Header.Get("Content-Range"), want) {
+		return fmt.Errorf("unexpected Content-Range %q for bytes %d-%d", resp.Header.Get("Content-Range"), r.start, r.end)
+	}
+
+	var body io.Reader = resp.Body
+	var idle *idleReader
+	if opts.IdleTimeout > 0 {
+		idle = newIdleReader(resp.Body, opts.IdleTimeout, cancel)
+		defer idle.stop()
+		body = idle
+	}
+	length := r.end - r.start + 1
+	body = io.LimitReader(body, length)
+	if opts.RejectHTML && r.start == 0 {
+		if body, err = sniffHTML(body, fileURL, opts.ExpectedContentType); err != nil {
+			return err
+		}
+	}
+
+	var dst io.Writer = io.NewOffsetWriter(f, r.start)
+	if opts.Progress != nil {
+		dst = progressWriter{w: dst, fn: opts.Progress}
+	}
+	n, err := io.Copy(dst, body)
+	if err != nil {
+		if idle != nil && idle.expired() {
+			return fmt.Errorf("%w (%s)", ErrIdleTimeout, opts.IdleTimeout)
+		}
+		return err
+	}
+	if n != length {
+		return fmt.Errorf("bytes %d-%d: %w: got %d of %d bytes", r.start, r.end, io.ErrUnexpectedEOF, n, length)
+	}
+	return nil
+}
+
+// setRangeHeaders выставляет заголовки запросов частей. Сжатие отключается:
+// диапазоны должны относиться к байтам самого файла, а не сжатого потока.
+func setRangeHeaders(req *http.Request, opts Options) {
+	setHeaders(req, opts)
+	applyUserinfo(req)
+	req.Header.Set("Accept-Encoding", "identity")
+}
+
+// identityEncoding сообщает, что ответ передаётся без Content-Encoding.
+func identityEncoding(encoding string) bool {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	return encoding == "" || encoding == "identity"
+}
@@ -0,0 +1,50 @@
+package download
+
+import (
+	"context"
+	"sync"
+)
+
+// ConnLimiter ограничивает число одновременных запросов к источникам во
+// всех скачиваниях сразу — воркерах, частях параллельного скачивания,
+// HEAD-запросах, — чтобы не исчерпать файловые дескрипторы. Слот занимается
+// перед запросом и освобождается, когда ответ прочитан и закрыт; FTP-сессия
+// занимает один слот. Простаивающие соединения пула транспорта не
+// учитываются: их ограничивает TransportConfig.MaxIdleConns. Безопасен для
+// параллельного использования; nil — без ограничения.
+type ConnLimiter struct {
+	slots chan struct{}
+}
+
+// NewConnLimiter возвращает ограничитель на n одновременных соединений.
+// При n <= 0 возвращает nil — ограничения нет.
+func NewConnLimiter(n int) *ConnLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &ConnLimiter{slots: make(chan struct{}, n)}
+}
+
+// InUse возвращает число занятых слотов.
+func (l *ConnLimiter) InUse() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.slots)
+}
+
+// acquire занимает слот, ожидая его не дольше, чем до отмены ctx.
+// Возвращённую функцию освобождения можно вызывать повторно: слот
+// освобождается один раз.
+func (l *ConnLimiter) acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+	var once sync.Once
+	return func() { once.Do(func() { <-l.slots }) }, nil
+}
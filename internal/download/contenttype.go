@@ -0,0 +1,87 @@
+package download
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ErrUnexpectedContentType возвращается, если Content-Type ответа не
+// соответствует Options.ExpectedContentType.
+var ErrUnexpectedContentType = errors.New("unexpected content type")
+
+// checkContentType сравнивает Content-Type ответа actual с ожидаемым
+// expected. Ожидаемый тип "application/pdf" должен совпасть с типом ответа
+// без параметров; "image/" или "image/*" — задаёт префикс. Регистр не
+// учитывается. Отсутствующий заголовок допускается только при allowMissing.
+func checkContentType(actual, expected string, allowMissing bool) error {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	if expected == "" {
+		return nil
+	}
+	if actual == "" {
+		if allowMissing {
+			return nil
+		}
+		return fmt.Errorf("%w: missing Content-Type, want %s", ErrUnexpectedContentType, expected)
+	}
+	media, _, err := mime.ParseMediaType(actual)
+	if err != nil {
+		media = strings.ToLower(strings.TrimSpace(strings.Split(actual, ";")[0]))
+	}
+	if prefix, ok := strings.CutSuffix(expected, "*"); ok || strings.HasSuffix(expected, "/") {
+		if strings.HasPrefix(media, prefix) {
+			return nil
+		}
+	} else if media == expected {
+		return nil
+	}
+	return fmt.Errorf("%w: got %s, want %s", ErrUnexpectedContentType, media, expected)
+}
+
+// ErrHTMLResponse возвращается при включённом Options.RejectHTML, если
+// вместо ожидаемого файла сервер вернул HTML-страницу.
+var ErrHTMLResponse = errors.New("server returned an HTML page instead of the file")
+
+// sniffLen — сколько первых байт тела анализирует http.DetectContentType.
+const sniffLen = 512
+
+// sniffHTML читает начало тела r и проверяет, не HTML ли это, когда по
+// ожидаемому типу или расширению в URL ожидается не HTML. Возвращает
+// читатель, который отдаёт прочитанные байты и затем остаток r, чтобы
+// ничего не потерять при записи.
+func sniffHTML(r io.Reader, fileURL, expected string) (io.Reader, error) {
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	buf = buf[:n]
+	r = io.MultiReader(bytes.NewReader(buf), r)
+	if n > 0 && strings.HasPrefix(http.DetectContentType(buf), "text/html") && !expectsHTML(fileURL, expected) {
+		return r, ErrHTMLResponse
+	}
+	return r, nil
+}
+
+// expectsHTML сообщает, допустим ли HTML в ответе: если ожидаемый тип не
+// задан, решение принимается по расширению файла в пути URL. URL без
+// расширения или с неизвестным расширением ничего не говорит о типе, и HTML
+// считается допустимым.
+func expectsHTML(fileURL, expected string) bool {
+	if expected = strings.TrimSpace(expected); expected != "" {
+		return checkContentType("text/html", expected, false) == nil
+	}
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return true
+	}
+	typ := mime.TypeByExtension(strings.ToLower(path.Ext(u.Path)))
+	return typ == "" || strings.HasPrefix(typ, "text/html") || strings.HasPrefix(typ, "application/xhtml")
+}
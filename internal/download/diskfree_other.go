@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package download
+
+import "errors"
+
+// diskFree на этой платформе не поддерживается; проверка места пропускается.
+func diskFree(dir string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package download
+
+import "golang.org/x/sys/unix"
+
+// diskFree возвращает число байт, доступных непривилегированному процессу
+// на файловой системе, содержащей dir.
+func diskFree(dir string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package download
+
+import "golang.org/x/sys/windows"
+
+// diskFree возвращает число байт, доступных текущему пользователю на томе,
+// содержащем dir.
+func diskFree(dir string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var avail uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &avail, nil, nil); err != nil {
+		return 0, err
+	}
+	return avail, nil
+}
@@ -2,17 +2,191 @@ package download
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
+
+	"hh03012025/internal/redact"
 )
 
+// ErrIdleTimeout возвращается, если во время скачивания данные не поступали
+// дольше Options.IdleTimeout.
+var ErrIdleTimeout = errors.New("idle timeout exceeded")
+
+// ErrTooLarge возвращается, если размер файла превышает Options.MaxBytes.
+var ErrTooLarge = errors.New("exceeds max size")
+
+// ErrTooManyRedirects возвращается, если число редиректов превысило
+// Options.MaxRedirects.
+var ErrTooManyRedirects = errors.New("redirect limit exceeded")
+
+// ErrRedirectHostMismatch возвращается, если при включённом
+// Options.SameHostRedirects редирект ведёт на другой хост или понижает схему
+// с https до http.
+var ErrRedirectHostMismatch = errors.New("redirect to a different host is not allowed")
+
+// ErrSizeMismatch возвращается, если число полученных байт не совпало с
+// размером, заявленным источником (Content-Length): соединение оборвалось
+// посреди передачи, и файл неполон.
+var ErrSizeMismatch = errors.New("size mismatch")
+
+// DefaultMaxRedirects — число редиректов, допустимое при нулевом
+// Options.MaxRedirects.
+const DefaultMaxRedirects = 10
+
+// DefaultUserAgent — User-Agent сервиса по умолчанию. Многие серверы
+// отклоняют запросы со стандартным "Go-http-client".
+const DefaultUserAgent = "hh03012025-downloader/1.0"
+
+// Result описывает итог успешного скачивания.
+type Result struct {
+	// FinalURL — URL, с которого фактически получен файл (после редиректов).
+	FinalURL string
+	// StatusCode — код ответа HTTP; 0 для источников не по HTTP.
+	StatusCode int
+	// Size — число записанных байт.
+	Size int64
+	// ContentType — значение заголовка Content-Type ответа.
+	ContentType string
+	// ETag и LastModified — валидаторы ответа для последующих условных
+	// запросов (см. Options.IfNoneMatch).
+	ETag         string
+	LastModified string
+	// NotModified означает, что сервер ответил 304 на условный запрос: файл
+	// dest не перезаписывался, а Size — его текущий размер.
+	NotModified bool
+}
+
+// ErrInsufficientDiskSpace возвращается, если на диске не хватает места для
+// файла с учётом Options.MinFreeBytes.
+var ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+
+// Options задаёт дополнительные параметры скачивания. Нулевое значение
+// соответствует поведению по умолчанию (без ограничений).
+type Options struct {
+	// IdleTimeout прерывает скачивание, если за это время из тела ответа не
+	// было прочитано ни одного байта. 0 — проверка отключена.
+	IdleTimeout time.Duration
+	// Headers — дополнительные заголовки запроса, например Authorization
+	// или User-Agent. Имеют приоритет над UserAgent и DefaultHeaders.
+	Headers map[string]string
+	// UserAgent — заголовок User-Agent запроса. Пустое значение оставляет
+	// стандартный User-Agent Go.
+	UserAgent string
+	// DefaultHeaders — заголовки, отправляемые с каждым запросом, например
+	// Accept. Переопределяют UserAgent.
+	DefaultHeaders http.Header
+	// Accept — заголовок Accept запроса, выбирающий представление ресурса
+	// (например, application/json или text/csv). Переопределяет
+	// DefaultHeaders; Headers имеют приоритет. Пустое значение — без
+	// отдельного заголовка.
+	Accept string
+	// MaxBytes ограничивает размер скачиваемого файла. 0 — без ограничения.
+	MaxBytes int64
+	// Progress, если задан, вызывается после каждой записи на диск с числом
+	// только что записанных байт.
+	Progress func(n int64)
+	// OnResponse, если задан, вызывается после получения успешного ответа,
+	// до передачи тела, со сведениями о размере и типе содержимого.
+	OnResponse func(Info)
+	// CheckDiskSpace включает проверку свободного места перед записью: должно
+	// хватать на Content-Length плюс MinFreeBytes. Если размер неизвестен,
+	// требуется только MinFreeBytes.
+	CheckDiskSpace bool
+	// MinFreeBytes — запас свободного места, который должен остаться на диске.
+	MinFreeBytes int64
+	// Reserve, если задан, вызывается перед передачей тела с размером файла
+	// (-1, если он неизвестен), например чтобы учесть файл в общем лимите
+	// места. Ошибка прерывает скачивание. При повторных запросах одного
+	// файла может вызываться несколько раз.
+	Reserve func(size int64) error
+	// MaxRedirects — максимальное число редиректов. 0 — DefaultMaxRedirects,
+	// отрицательное значение запрещает редиректы.
+	MaxRedirects int
+	// SameHostRedirects разрешает только редиректы на тот же хост без
+	// понижения схемы с https до http.
+	SameHostRedirects bool
+	// HostPolicy ограничивает хосты исходного URL и всех редиректов.
+	HostPolicy HostPolicy
+	// BlockPrivateIPs запрещает соединения с приватными, loopback и
+	// link-local адресами (защита от SSRF).
+	BlockPrivateIPs bool
+	// DecodeContentEncoding распаковывает ответ с Content-Encoding gzip или
+	// deflate перед записью на диск. Без него сохраняются байты в том виде,
+	// в котором их отправил сервер.
+	DecodeContentEncoding bool
+	// ExpectedContentType — ожидаемый Content-Type ответа или его префикс
+	// ("image/"); при несовпадении скачивание завершается с
+	// ErrUnexpectedContentType. Пустое значение отключает проверку.
+	ExpectedContentType string
+	// AllowMissingContentType допускает ответ без Content-Type при заданном
+	// ExpectedContentType.
+	AllowMissingContentType bool
+	// RejectHTML включает эвристику по первым байтам тела: если это HTML, а
+	// по ExpectedContentType или расширению в URL ожидается другой тип,
+	// скачивание завершается с ErrHTMLResponse.
+	RejectHTML bool
+	// IfNoneMatch и IfModifiedSince делают запрос условным. Если сервер
+	// ответил 304 Not Modified, существующий файл dest остаётся без изменений,
+	// а Result.NotModified устанавливается в true. Заголовки из Headers имеют
+	// приоритет.
+	IfNoneMatch     string
+	IfModifiedSince string
+	// Client — HTTP-клиент, общий для всех скачиваний (см. NewClient). Если
+	// не задан, для каждого запроса создаётся новый клиент со своим
+	// транспортом, и соединения не переиспользуются.
+	Client *http.Client
+	// Redactor, если задан, скрывает секреты в URL, попадающих в текст
+	// ошибок запроса.
+	Redactor *redact.Redactor
+	// TLSConfig — параметры TLS для источников вне HTTP-клиента (ftps и
+	// ftpes): корневые сертификаты и проверка сертификата. nil — системные
+	// настройки. HTTP использует TLS-настройки транспорта Client.
+	TLSConfig *tls.Config
+	// S3 — клиент для URL s3://. nil — клиент с настройками AWS по
+	// умолчанию.
+	S3 *S3Client
+	// Chunks > 1 включает скачивание файла параллельно несколькими частями
+	// с заголовком Range, если сервер поддерживает диапазоны. Условные
+	// запросы (IfNoneMatch, IfModifiedSince) всегда идут одним потоком.
+	Chunks int
+	// ChunkMinSize — минимальный размер файла для скачивания частями.
+	// 0 — DefaultChunkMinSize.
+	ChunkMinSize int64
+	// Conns ограничивает число одновременных запросов к источникам, общее
+	// для всех скачиваний (см. ConnLimiter). nil — без ограничения.
+	Conns *ConnLimiter
+	// ConnectTimeout ограничивает установку соединений FTP (см.
+	// TransportConfig.ConnectTimeout). Для HTTP таймаут задаётся
+	// транспортом Client.
+	ConnectTimeout time.Duration
+	// Resume включает продолжение скачивания по HTTP: если от прошлой
+	// попытки остался временный файл dest+".part", запрашивается только
+	// недостающая часть (Range) с заголовком If-Range: ResumeValidator.
+	// Если файл на сервере изменился, сервер отдаёт его целиком, и
+	// скачивание начинается заново. Без ResumeValidator файл всегда
+	// скачивается заново. Условные запросы отключают продолжение.
+	Resume bool
+	// ResumeValidator — Info.Validator ответа, с которым был начат
+	// временный файл.
+	ResumeValidator string
+}
+
 // DeriveFileName определяет имя файла для сохранения.
 // Использует последний сегмент пути URL, если он есть; иначе
-// генерирует имя вида "file_<индекс>". Параметры после "?" отбрасываются.
+// генерирует имя вида "file_<индекс>". Параметры после "?" отбрасываются,
+// разделители путей заменяются на "_", а имена "." и ".." не допускаются,
+// поэтому результат всегда остаётся внутри каталога задачи.
 func DeriveFileName(rawURL string, index int) string {
 	u, err := url.Parse(rawURL)
 	if err != nil || u.Path == "" || u.Path == "/" {
@@ -25,60 +199,384 @@ func DeriveFileName(rawURL string, index int) string {
 	if i := strings.Index(name, "?"); i != -1 {
 		name = name[:i]
 	}
-	if name == "" {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == 0 {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
 		name = fmt.Sprintf("file_%d", index)
 	}
 	return name
 }
 
+// UniqueFileName возвращает name, если оно ещё не занято в used, иначе
+// добавляет перед расширением суффикс "(n)": "data.zip" -> "data(1).zip".
+// Выбранное имя отмечается в used. Сравнение без учёта регистра, чтобы
+// имена не конфликтовали и на нечувствительных к регистру файловых системах.
+func UniqueFileName(name string, used map[string]bool) string {
+	candidate := name
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 1; used[strings.ToLower(candidate)]; n++ {
+		candidate = fmt.Sprintf("%s(%d)%s", base, n, ext)
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
 // DownloadWithContext скачивает файл по заданному URL и записывает его в dest.
 // Скачивание отменяется через ctx. Каталоги для dest должны быть созданы
 // заранее. Запись ведётся во временный файл и затем атомарно переименовывается
-// в конечное имя, чтобы избежать частичных файлов при сбоях.
-func DownloadWithContext(ctx context.Context, fileURL, dest string) error {
+// в конечное имя, чтобы избежать частичных файлов при сбоях. Параметры opts
+// задают дополнительные ограничения (например, таймаут простоя). Учётные
+// данные из URL (user:pass@) отправляются заголовком Authorization: Basic.
+// При Options.Chunks > 1 большой файл скачивается параллельно частями (см.
+// Options.Chunks), а если сервер не поддерживает Range — одним потоком.
+// Result содержит сведения об ответе, в том числе итоговый URL после
+// редиректов.
+func DownloadWithContext(ctx context.Context, fileURL, dest string, opts Options) (Result, error) {
+	var res Result
+	if err := opts.HostPolicy.CheckURL(fileURL); err != nil {
+		return res, err
+	}
+	conditional := opts.IfNoneMatch != "" || opts.IfModifiedSince != ""
+	var offset int64
+	if !conditional {
+		offset = resumeOffset(dest, opts)
+	}
+	if opts.Chunks > 1 && !conditional && offset == 0 {
+		if res, ok, err := downloadChunked(ctx, fileURL, dest, opts); ok || err != nil {
+			return res, err
+		}
+	}
+	// Собственная отмена нужна, чтобы прервать чтение по таймауту простоя
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Создаем запрос с контекстом для отмены
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
 	if err != nil {
-		return err
+		return res, err
+	}
+	setHeaders(req, opts)
+	applyUserinfo(req)
+	if opts.IfNoneMatch != "" && req.Header.Get("If-None-Match") == "" {
+		req.Header.Set("If-None-Match", opts.IfNoneMatch)
+	}
+	if opts.IfModifiedSince != "" && req.Header.Get("If-Modified-Since") == "" {
+		req.Header.Set("If-Modified-Since", opts.IfModifiedSince)
+	}
+	if offset > 0 {
+		// диапазон относится к байтам файла, а не сжатого потока
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", opts.ResumeValidator)
+		req.Header.Set("Accept-Encoding", "identity")
 	}
 
-	// Используем клиент без фиксированного таймаута; полагаемся на контекст для отмены
-	client := &http.Client{Timeout: 0}
-	resp, err := client.Do(req)
+	release, err := opts.Conns.acquire(ctx)
 	if err != nil {
-		return err
+		return res, err
+	}
+	defer release()
+	resp, err := client(opts).Do(req)
+	if err != nil {
+		return res, privateAddressError(redactError(err, opts))
 	}
 	defer resp.Body.Close()
+	res.FinalURL = resp.Request.URL.String()
+	res.StatusCode = resp.StatusCode
+	res.ContentType = resp.Header.Get("Content-Type")
+	res.ETag = resp.Header.Get("ETag")
+	res.LastModified = resp.Header.Get("Last-Modified")
+
+	// Файл не изменился с прошлого скачивания — оставляем его как есть
+	if conditional && resp.StatusCode == http.StatusNotModified {
+		fi, err := os.Stat(dest)
+		if err != nil {
+			return res, err
+		}
+		res.NotModified = true
+		res.Size = fi.Size()
+		return res, nil
+	}
+
+	// Временный файл длиннее файла на сервере — продолжать нечего,
+	// скачиваем заново
+	if offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		resp.Body.Close()
+		release()
+		os.Remove(dest + ".part")
+		opts.Resume = false
+		return DownloadWithContext(ctx, fileURL, dest, opts)
+	}
 
 	// Проверяем статус ответа, если он не в диапазоне 2xx — ошибка
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("неправильный статус: %s", resp.Status)
+		return res, newStatusError(resp)
+	}
+
+	// На 206 сервер подтвердил, что файл не изменился (If-Range), и отдаёт
+	// продолжение; на 200 — файл целиком, и временный файл пишется заново
+	size := resp.ContentLength
+	if offset > 0 && resp.StatusCode == http.StatusPartialContent {
+		if size, err = resumedSize(resp, offset); err != nil {
+			return res, err
+		}
+	} else {
+		offset = 0
+	}
+
+	if opts.OnResponse != nil {
+		opts.OnResponse(Info{Size: size, ContentType: res.ContentType,
+			Validator: rangeValidator(res.ETag, res.LastModified), Resumed: offset})
+	}
+
+	// Сервер может вернуть страницу ошибки со статусом 200 вместо файла
+	if err := checkContentType(res.ContentType, opts.ExpectedContentType, opts.AllowMissingContentType); err != nil {
+		return res, err
+	}
+
+	// Если сервер заранее сообщил размер, отказываемся до начала передачи
+	if opts.MaxBytes > 0 && size > opts.MaxBytes {
+		return res, fmt.Errorf("%w: %d > %d bytes", ErrTooLarge, size, opts.MaxBytes)
+	}
+
+	if opts.Reserve != nil {
+		if err := opts.Reserve(size); err != nil {
+			return res, err
+		}
+	}
+	if opts.CheckDiskSpace {
+		if err := checkDiskSpace(filepath.Dir(dest), resp.ContentLength, opts.MinFreeBytes); err != nil {
+			return res, err
+		}
+	}
+
+	// Следим за простоем: если байты перестали поступать, отменяем запрос
+	var body io.Reader = resp.Body
+	var idle *idleReader
+	if opts.IdleTimeout > 0 {
+		idle = newIdleReader(resp.Body, opts.IdleTimeout, cancel)
+		defer idle.stop()
+		body = idle
+	}
+	// Транспорт распаковывает ответ сам, только если заголовок
+	// Accept-Encoding выставил он; при заданном вручную заголовке или
+	// непрошеном сжатии распаковываем здесь
+	decoded := false
+	if opts.DecodeContentEncoding && !resp.Uncompressed {
+		decoded = !identityEncoding(resp.Header.Get("Content-Encoding"))
+		dec, err := decodeBody(body, resp.Header.Get("Content-Encoding"))
+		if err != nil {
+			return res, err
+		}
+		defer dec.Close()
+		body = dec
+	}
+	// Размер может быть неизвестен: читаем не больше лимита плюс один байт,
+	// чтобы заметить превышение
+	if opts.MaxBytes > 0 {
+		body = io.LimitReader(body, opts.MaxBytes-offset+1)
+	}
+
+	// начало продолжаемого файла уже проверено при первой попытке
+	if opts.RejectHTML && offset == 0 {
+		if body, err = sniffHTML(body, fileURL, opts.ExpectedContentType); err != nil {
+			return res, err
+		}
 	}
 
-	// Создаем временный файл в той же директории
-	tmp := dest + ".part"
-	tmpFile, err := os.Create(tmp)
+	// Сверяем число байт с Content-Length, чтобы оборванная передача не
+	// считалась успешной. После распаковки размер отличается от заявленного,
+	// а неполный сжатый поток обнаруживает сам декодер
+	expected := size
+	if decoded {
+		expected = -1
+	}
+	// начало файла имеет смысл сохранять, только если продолжение
+	// возможно: сервер сообщил валидатор для If-Range
+	resumable := opts.Resume && !decoded && rangeValidator(res.ETag, res.LastModified) != ""
+	n, err := writePart(dest, body, offset, expected, opts, idle, resumable, nil)
 	if err != nil {
-		return err
+		return res, err
 	}
-	defer tmpFile.Close()
+	res.Size = n
+	return res, nil
+}
 
-	// Копируем тело ответа в временный файл
-	if _, err := io.Copy(tmpFile, resp.Body); err != nil {
-		return err
+// resumeOffset возвращает размер временного файла dest+".part", с которого
+// можно продолжить скачивание, или 0, если продолжение выключено, не с чем
+// сверить файл на сервере или временного файла нет.
+func resumeOffset(dest string, opts Options) int64 {
+	if !opts.Resume || opts.ResumeValidator == "" {
+		return 0
 	}
+	fi, err := os.Stat(dest + ".part")
+	if err != nil || !fi.Mode().IsRegular() {
+		return 0
+	}
+	return fi.Size()
+}
 
-	// Обеспечиваем, чтобы данные были записаны в файл
-	if err := tmpFile.Sync(); err != nil {
-		return err
+// resumedSize проверяет, что ответ 206 продолжает файл с байта offset, и
+// возвращает полный размер файла (-1, если он неизвестен).
+func resumedSize(resp *http.Response, offset int64) (int64, error) {
+	cr := resp.Header.Get("Content-Range")
+	var start, end int64
+	var total string
+	if _, err := fmt.Sscanf(cr, "bytes %d-%d/%s", &start, &end, &total); err != nil || start != offset {
+		return 0, fmt.Errorf("unexpected Content-Range %q for resume from byte %d", cr, offset)
+	}
+	if !identityEncoding(resp.Header.Get("Content-Encoding")) {
+		return 0, fmt.Errorf("cannot resume: partial response is %s-encoded", resp.Header.Get("Content-Encoding"))
 	}
+	if total == "*" {
+		return -1, nil
+	}
+	return strconv.ParseInt(total, 10, 64)
+}
 
-	// Закрываем временный файл
-	if err := tmpFile.Close(); err != nil {
-		return err
+// rangeValidator выбирает валидатор для If-Range: сильный ETag, а если его
+// нет — Last-Modified. Слабый ETag для диапазонов не годится.
+func rangeValidator(etag, lastModified string) string {
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		return lastModified
 	}
+	return etag
+}
 
-	// Переименовываем временный файл в целевой
-	return os.Rename(tmp, dest)
+// setHeaders выставляет заголовки запроса из opts: сначала UserAgent, затем
+// DefaultHeaders и, наконец, заголовки файла Headers.
+func setHeaders(req *http.Request, opts Options) {
+	if opts.UserAgent != "" {
+		req.Header.Set("User-Agent", opts.UserAgent)
+	}
+	for k, v := range opts.DefaultHeaders {
+		req.Header[http.CanonicalHeaderKey(k)] = v
+	}
+	if opts.Accept != "" {
+		req.Header.Set("Accept", opts.Accept)
+	}
+	for k, v := range opts.Headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// NewClient создаёт HTTP-клиент для запросов к источникам через transport
+// с политикой редиректов из opts (MaxRedirects, SameHostRedirects,
+// HostPolicy). Клиент без фиксированного таймаута: отмена выполняется через
+// контекст. Клиент безопасен для параллельного использования и передаётся в
+// скачивания через Options.Client.
+func NewClient(transport http.RoundTripper, opts Options) *http.Client {
+	return &http.Client{
+		Timeout:       0,
+		Transport:     transport,
+		CheckRedirect: redirectPolicy(opts),
+	}
+}
+
+// client возвращает Options.Client или, если он не задан, новый клиент по
+// opts.
+func client(opts Options) *http.Client {
+	if opts.Client != nil {
+		return opts.Client
+	}
+	return NewClient(NewTransport(TransportConfig{
+		BlockPrivateIPs:       opts.BlockPrivateIPs,
+		DecodeContentEncoding: opts.DecodeContentEncoding,
+	}), opts)
+}
+
+// redirectPolicy возвращает функцию CheckRedirect для http.Client,
+// ограничивающую число редиректов, смену хоста и хосты по HostPolicy.
+func redirectPolicy(opts Options) func(*http.Request, []*http.Request) error {
+	limit := opts.MaxRedirects
+	if limit == 0 {
+		limit = DefaultMaxRedirects
+	}
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > limit {
+			return fmt.Errorf("%w (%d)", ErrTooManyRedirects, max(limit, 0))
+		}
+		if opts.SameHostRedirects {
+			orig := via[0].URL
+			if req.URL.Host != orig.Host || (orig.Scheme == "https" && req.URL.Scheme != "https") {
+				return fmt.Errorf("%w: %s", ErrRedirectHostMismatch, req.URL.Host)
+			}
+		}
+		return opts.HostPolicy.CheckHost(req.URL.Hostname())
+	}
+}
+
+// checkDiskSpace проверяет, что в каталоге dir хватит места на size байт
+// (если размер известен) с запасом margin. На платформах без поддержки
+// проверка пропускается.
+func checkDiskSpace(dir string, size, margin int64) error {
+	free, err := diskFree(dir)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("check disk space: %w", err)
+	}
+	need := margin
+	if size > 0 {
+		need += size
+	}
+	if need > 0 && free < uint64(need) {
+		return fmt.Errorf("%w: need %d bytes, %d available", ErrInsufficientDiskSpace, need, free)
+	}
+	return nil
+}
+
+// progressWriter сообщает о каждой успешной записи через fn.
+type progressWriter struct {
+	w  io.Writer
+	fn func(n int64)
+}
+
+func (pw progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	if n > 0 {
+		pw.fn(int64(n))
+	}
+	return n, err
+}
+
+// idleReader оборачивает тело ответа и перезапускает таймер при каждом
+// успешном чтении. Если таймер сработал, вызывается cancel, что прерывает
+// зависшее чтение.
+type idleReader struct {
+	r       io.Reader
+	timeout time.Duration
+	timer   *time.Timer
+	fired   atomic.Bool
+}
+
+func newIdleReader(r io.Reader, timeout time.Duration, cancel context.CancelFunc) *idleReader {
+	ir := &idleReader{r: r, timeout: timeout}
+	ir.timer = time.AfterFunc(timeout, func() {
+		ir.fired.Store(true)
+		cancel()
+	})
+	return ir
+}
+
+func (ir *idleReader) Read(p []byte) (int, error) {
+	n, err := ir.r.Read(p)
+	if n > 0 && !ir.fired.Load() {
+		ir.timer.Reset(ir.timeout)
+	}
+	return n, err
+}
+
+// expired сообщает, было ли скачивание прервано по таймауту простоя.
+func (ir *idleReader) expired() bool {
+	return ir.fired.Load()
+}
 
+func (ir *idleReader) stop() {
+	ir.timer.Stop()
 }
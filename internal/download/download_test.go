@@ -0,0 +1,354 @@
+package download
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeriveFileName(t *testing.T) {
+	tests := []struct {
+		url   string
+		index int
+		want  string
+	}{
+		{"https://example.com/data.zip", 0, "data.zip"},
+		{"https://example.com/dir/report.pdf?token=x", 1, "report.pdf"},
+		{"https://example.com/", 2, "file_2"},
+		{"https://example.com", 3, "file_3"},
+		{"https://example.com/dir/", 4, "file_4"},
+		{"https://example.com/a%5Cb", 5, "a_b"},
+		{"https://example.com/..", 6, "file_6"},
+		{"://bad", 7, "file_7"},
+	}
+	for _, tt := range tests {
+		if got := DeriveFileName(tt.url, tt.index); got != tt.want {
+			t.Errorf("DeriveFileName(%q, %d) = %q, want %q", tt.url, tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueFileName(t *testing.T) {
+	used := make(map[string]bool)
+	names := []string{"data.zip", "data.zip", "DATA.ZIP", "data", "data", "archive.tar.gz", "archive.tar.gz"}
+	want := []string{"data.zip", "data(1).zip", "DATA(2).ZIP", "data", "data(1)", "archive.tar.gz", "archive.tar(1).gz"}
+	for i, name := range names {
+		if got := UniqueFileName(name, used); got != want[i] {
+			t.Errorf("UniqueFileName(%q) #%d = %q, want %q", name, i, got, want[i])
+		}
+	}
+}
+
+func TestDownloadConditional(t *testing.T) {
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-None-Match") == `"v2"` || r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("new content"))
+	}))
+	defer src.Close()
+
+	tests := []struct {
+		name            string
+		ifNoneMatch     string
+		ifModifiedSince string
+		wantNotModified bool
+		wantContent     string
+	}{
+		{"matching ETag", `"v2"`, "", true, "old"},
+		{"matching Last-Modified", "", lastModified, true, "old"},
+		{"stale ETag", `"v1"`, "", false, "new content"},
+		{"unconditional", "", "", false, "new content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "file")
+			if err := os.WriteFile(dest, []byte("old"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			res, err := DownloadWithContext(t.Context(), src.URL+"/file", dest, Options{
+				IfNoneMatch:     tt.ifNoneMatch,
+				IfModifiedSince: tt.ifModifiedSince,
+			})
+			if err != nil {
+				t.Fatalf("DownloadWithContext: %v", err)
+			}
+			if res.NotModified != tt.wantNotModified {
+				t.Errorf("NotModified = %v, want %v", res.NotModified, tt.wantNotModified)
+			}
+			if res.Size != int64(len(tt.wantContent)) {
+				t.Errorf("Size = %d, want %d", res.Size, len(tt.wantContent))
+			}
+			if res.ETag != `"v2"` || res.LastModified != lastModified {
+				t.Errorf("validators = %q, %q", res.ETag, res.LastModified)
+			}
+			if data, _ := os.ReadFile(dest); string(data) != tt.wantContent {
+				t.Errorf("file = %q, want %q", data, tt.wantContent)
+			}
+		})
+	}
+}
+
+// BenchmarkDownloadWithContext сравнивает скачивание с новым клиентом на
+// каждый вызов и с общим клиентом, переиспользующим соединения.
+func BenchmarkDownloadWithContext(b *testing.B) {
+	const downloads = 100
+	body := bytes.Repeat([]byte("x"), 16<<10)
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	defer src.Close()
+	dest := filepath.Join(b.TempDir(), "file")
+
+	benchmarks := []struct {
+		name   string
+		client *http.Client
+	}{
+		{"per-call client", nil},
+		{"shared client", NewClient(NewTransport(TransportConfig{}), Options{})},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for b.Loop() {
+				for range downloads {
+					if _, err := DownloadWithContext(b.Context(), src.URL+"/file", dest, Options{Client: bm.client}); err != nil {
+						b.Fatal(err)
+					}
+				}
+				// соединения клиентов на каждый вызов иначе копятся до
+				// IdleConnTimeout
+				src.CloseClientConnections()
+			}
+		})
+	}
+}
+
+func TestDownloadURLCredentials(t *testing.T) {
+	var gotAuth string
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer src.Close()
+	host := strings.TrimPrefix(src.URL, "http://")
+
+	tests := []struct {
+		name     string
+		url      string
+		headers  map[string]string
+		wantAuth string
+	}{
+		{"user and password", "http://alice:s3cret@" + host + "/f", nil, "Basic YWxpY2U6czNjcmV0"},
+		{"user only", "http://alice@" + host + "/f", nil, "Basic YWxpY2U6"},
+		{"escaped password", "http://alice:p%40ss@" + host + "/f", nil, "Basic YWxpY2U6cEBzcw=="},
+		{"explicit header wins", "http://alice:s3cret@" + host + "/f", map[string]string{"Authorization": "Bearer tok"}, "Bearer tok"},
+		{"no credentials", src.URL + "/f", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAuth = ""
+			res, err := DownloadWithContext(t.Context(), tt.url, filepath.Join(t.TempDir(), "f"), Options{Headers: tt.headers})
+			if err != nil {
+				t.Fatalf("DownloadWithContext: %v", err)
+			}
+			if gotAuth != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", gotAuth, tt.wantAuth)
+			}
+			if strings.Contains(res.FinalURL, "alice") {
+				t.Errorf("FinalURL %q contains credentials", res.FinalURL)
+			}
+		})
+	}
+}
+
+func TestDownloadTruncatedBody(t *testing.T) {
+	const content = "0123456789"
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/full":
+			_, _ = w.Write([]byte(content))
+		case "/short":
+			// заявленный размер больше отправленного: соединение закрывается
+			// посреди передачи
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(content[:4]))
+			w.(http.Flusher).Flush()
+			conn, _, _ := http.NewResponseController(w).Hijack()
+			conn.Close()
+		case "/chunked":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(content[:4]))
+			w.(http.Flusher).Flush()
+			conn, _, _ := http.NewResponseController(w).Hijack()
+			conn.Close()
+		}
+	}))
+	defer src.Close()
+
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{"/full", nil},
+		{"/short", ErrSizeMismatch},
+		{"/chunked", io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "file")
+			res, err := DownloadWithContext(t.Context(), src.URL+tt.path, dest, Options{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				if data, _ := os.ReadFile(dest); res.Size != int64(len(content)) || string(data) != content {
+					t.Errorf("size %d, content %q", res.Size, data)
+				}
+				return
+			}
+			for _, p := range []string{dest, dest + ".part"} {
+				if _, err := os.Stat(p); !os.IsNotExist(err) {
+					t.Errorf("%s left after a truncated download: %v", filepath.Base(p), err)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	const content = "0123456789"
+	var gotRange string
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRange = r.Header.Get("Range")
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer src.Close()
+
+	tests := []struct {
+		name      string
+		part      string
+		validator string
+		wantRange string
+	}{
+		{"same version continues", "0123", `"v2"`, "bytes=4-"},
+		{"changed version restarts", "abcd", `"v1"`, "bytes=4-"},
+		{"no validator restarts", "abcd", "", ""},
+		{"part longer than file restarts", "abcdefghijkl", `"v2"`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "file")
+			if err := os.WriteFile(dest+".part", []byte(tt.part), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			var resumed int64 = -1
+			res, err := DownloadWithContext(t.Context(), src.URL+"/file", dest, Options{
+				Resume:          true,
+				ResumeValidator: tt.validator,
+				OnResponse:      func(info Info) { resumed = info.Resumed },
+			})
+			if err != nil {
+				t.Fatalf("DownloadWithContext: %v", err)
+			}
+			if data, _ := os.ReadFile(dest); string(data) != content || res.Size != int64(len(content)) {
+				t.Errorf("file = %q (size %d), want %q", data, res.Size, content)
+			}
+			// после 416 запрос повторяется без Range: важен последний запрос
+			if gotRange != tt.wantRange {
+				t.Errorf("last Range = %q, want %q", gotRange, tt.wantRange)
+			}
+			wantResumed := int64(0)
+			if tt.name == "same version continues" {
+				wantResumed = int64(len(tt.part))
+			}
+			if resumed != wantResumed {
+				t.Errorf("Info.Resumed = %d, want %d", resumed, wantResumed)
+			}
+			if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+				t.Errorf(".part left after a completed download: %v", err)
+			}
+		})
+	}
+}
+
+func TestDownloadPartCleanup(t *testing.T) {
+	const content = "0123456789"
+	// truncated отдаёт начало файла и обрывает соединение
+	truncated := func(etag string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if etag != "" {
+				w.Header().Set("ETag", etag)
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write([]byte(content[:4]))
+			w.(http.Flusher).Flush()
+			conn, _, _ := http.NewResponseController(w).Hijack()
+			conn.Close()
+		}
+	}
+	full := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(content))
+	}
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		resume   bool
+		destDir  bool // dest — непустой каталог, переименование не удаётся
+		wantErr  error
+		wantPart string
+	}{
+		{name: "status error", handler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}, resume: true},
+		{name: "interrupted resumable", handler: truncated(`"v1"`), resume: true, wantErr: ErrSizeMismatch, wantPart: content[:4]},
+		{name: "interrupted without resume", handler: truncated(`"v1"`), wantErr: ErrSizeMismatch},
+		{name: "interrupted without validator", handler: truncated(""), resume: true, wantErr: ErrSizeMismatch},
+		{name: "too large", handler: full, resume: true, wantErr: ErrTooLarge},
+		{name: "rename error", handler: full, resume: true, destDir: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := httptest.NewServer(tt.handler)
+			defer src.Close()
+			dest := filepath.Join(t.TempDir(), "file")
+			if tt.destDir {
+				if err := os.MkdirAll(filepath.Join(dest, "sub"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			opts := Options{Resume: tt.resume}
+			if tt.wantErr == ErrTooLarge {
+				opts.MaxBytes = 4
+			}
+			_, err := DownloadWithContext(t.Context(), src.URL+"/file", dest, opts)
+			if err == nil {
+				t.Fatal("DownloadWithContext succeeded, want error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			data, err := os.ReadFile(dest + ".part")
+			switch {
+			case tt.wantPart != "" && (err != nil || string(data) != tt.wantPart):
+				t.Errorf(".part = %q, %v; want %q", data, err, tt.wantPart)
+			case tt.wantPart == "" && !os.IsNotExist(err):
+				t.Errorf(".part kept: %q, %v", data, err)
+			}
+		})
+	}
+}
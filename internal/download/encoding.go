@@ -0,0 +1,51 @@
+package download
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// decodeBody возвращает читатель, распаковывающий r согласно значению
+// заголовка Content-Encoding. Пустое значение и identity возвращают r без
+// изменений; для неизвестной кодировки возвращается ошибка, чтобы не
+// сохранить на диск данные в неожиданном формате.
+func decodeBody(r io.Reader, encoding string) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return io.NopCloser(r), nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("decode gzip: %w", err)
+		}
+		return zr, nil
+	case "deflate":
+		return newDeflateReader(r)
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
+
+// newDeflateReader распаковывает deflate. По стандарту это поток zlib, но
+// часть серверов отправляет «голый» deflate без заголовка, поэтому формат
+// определяется по первым двум байтам.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	hdr, err := br.Peek(2)
+	if err != nil {
+		return nil, fmt.Errorf("decode deflate: %w", err)
+	}
+	// Заголовок zlib: метод 8 в младших битах CMF и CMF*256+FLG кратно 31
+	if hdr[0]&0x0f == 8 && (uint16(hdr[0])<<8|uint16(hdr[1]))%31 == 0 {
+		zr, err := zlib.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("decode deflate: %w", err)
+		}
+		return zr, nil
+	}
+	return flate.NewReader(br), nil
+}
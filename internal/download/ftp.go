@@ -0,0 +1,241 @@
+package download
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+)
+
+// ftpPorts — порты по умолчанию для схем FTP: ftps — FTP поверх TLS с самого
+// начала соединения (implicit), ftpes — FTP с переходом на TLS командой
+// AUTH TLS (explicit).
+var ftpPorts = map[string]string{"ftp": "21", "ftps": "990", "ftpes": "21"}
+
+// DownloadFTP скачивает файл по URL со схемой ftp, ftps или ftpes и
+// записывает его в dest так же, как DownloadWithContext: во временный файл
+// dest+".part" с атомарным переименованием. Учётные данные берутся из URL
+// (user:pass@), без них используется anonymous. Отмена ctx закрывает
+// соединения и прерывает передачу. Из opts учитываются ограничения хостов и
+// адресов, лимит размера, проверка свободного места, таймаут простоя,
+// ожидаемый тип (определяется по расширению), таймаут подключения и
+// Progress.
+func DownloadFTP(ctx context.Context, fileURL, dest string, opts Options) (Result, error) {
+	var res Result
+	// причина отмены по таймауту простоя попадает в ошибку через ftpError
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	u, c, release, err := dialFTP(ctx, fileURL, opts)
+	if err != nil {
+		return res, err
+	}
+	defer release()
+	defer c.Quit()
+	res.FinalURL = ftpDisplayURL(u)
+	res.ContentType = mime.TypeByExtension(path.Ext(u.Path))
+
+	// SIZE поддерживают не все серверы: тогда размер неизвестен
+	size, err := c.FileSize(u.Path)
+	if err != nil {
+		size = -1
+	}
+	if opts.OnResponse != nil {
+		opts.OnResponse(Info{Size: size, ContentType: res.ContentType})
+	}
+	if err := checkContentType(res.ContentType, opts.ExpectedContentType, opts.AllowMissingContentType); err != nil {
+		return res, err
+	}
+	if opts.MaxBytes > 0 && size > opts.MaxBytes {
+		return res, fmt.Errorf("%w: %d > %d bytes", ErrTooLarge, size, opts.MaxBytes)
+	}
+	if opts.Reserve != nil {
+		if err := opts.Reserve(size); err != nil {
+			return res, err
+		}
+	}
+	if opts.CheckDiskSpace {
+		if err := checkDiskSpace(filepath.Dir(dest), size, opts.MinFreeBytes); err != nil {
+			return res, err
+		}
+	}
+
+	resp, err := c.Retr(u.Path)
+	if err != nil {
+		return res, ftpError(ctx, err)
+	}
+	defer resp.Close()
+	var body io.Reader = resp
+	var idle *idleReader
+	if opts.IdleTimeout > 0 {
+		idle = newIdleReader(resp, opts.IdleTimeout, func() {
+			cancel(fmt.Errorf("%w (%s)", ErrIdleTimeout, opts.IdleTimeout))
+		})
+		defer idle.stop()
+		body = idle
+	}
+	if opts.MaxBytes > 0 {
+		body = io.LimitReader(body, opts.MaxBytes+1)
+	}
+
+	// ответ сервера после передачи сообщает, дошёл ли файл целиком
+	n, err := writePart(dest, body, 0, size, opts, idle, false, resp.Close)
+	if err != nil {
+		return res, ftpError(ctx, err)
+	}
+	res.Size = n
+	return res, nil
+}
+
+// preflightFTP подключается к FTP-серверу и узнаёт размер файла командой
+// SIZE, не начиная передачу. Проверки лимитов те же, что у Preflight.
+func preflightFTP(ctx context.Context, fileURL, dest string, opts Options) (Info, error) {
+	info := Info{Size: -1}
+	u, c, release, err := dialFTP(ctx, fileURL, opts)
+	if err != nil {
+		return info, err
+	}
+	defer release()
+	defer c.Quit()
+	info.ContentType = mime.TypeByExtension(path.Ext(u.Path))
+	if info.Size, err = c.FileSize(u.Path); err != nil {
+		return Info{Size: -1, ContentType: info.ContentType}, fmt.Errorf("%w: %v", ErrPreflightUnsupported, err)
+	}
+	if opts.MaxBytes > 0 && info.Size > opts.MaxBytes {
+		return info, fmt.Errorf("%w: %d > %d bytes", ErrTooLarge, info.Size, opts.MaxBytes)
+	}
+	if opts.CheckDiskSpace && info.Size > 0 {
+		if err := checkDiskSpace(filepath.Dir(dest), info.Size, opts.MinFreeBytes); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+// dialFTP разбирает fileURL, подключается к серверу и входит в систему.
+// Отмена ctx закрывает все соединения сессии (управляющее и соединения
+// данных) и прерывает зависшие операции. Функцию release нужно вызвать по
+// окончании работы с сессией; сессия занимает один слот Options.Conns.
+func dialFTP(ctx context.Context, fileURL string, opts Options) (u *url.URL, c *ftp.ServerConn, release func(), err error) {
+	if err := opts.HostPolicy.CheckURL(fileURL); err != nil {
+		return nil, nil, nil, err
+	}
+	u, err = url.Parse(fileURL)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defaultPort, ok := ftpPorts[u.Scheme]
+	if !ok || u.Host == "" {
+		return nil, nil, nil, fmt.Errorf("unsupported FTP URL %q", ftpDisplayURL(u))
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	releaseSlot, err := opts.Conns.acquire(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	conns := &ftpConns{ctx: ctx, timeout: opts.ConnectTimeout}
+	if opts.BlockPrivateIPs {
+		conns.control = denyPrivate
+	}
+	stop := context.AfterFunc(ctx, conns.close)
+	release = func() {
+		stop()
+		conns.close()
+		releaseSlot()
+	}
+
+	dialOpts := []ftp.DialOption{ftp.DialWithDialFunc(conns.dial)}
+	tlsConfig := &tls.Config{ServerName: u.Hostname()}
+	if opts.TLSConfig != nil {
+		tlsConfig = opts.TLSConfig.Clone()
+		tlsConfig.ServerName = u.Hostname()
+	}
+	switch u.Scheme {
+	case "ftps":
+		dialOpts = append(dialOpts, ftp.DialWithTLS(tlsConfig))
+	case "ftpes":
+		dialOpts = append(dialOpts, ftp.DialWithExplicitTLS(tlsConfig))
+	}
+	c, err = ftp.Dial(net.JoinHostPort(u.Hostname(), port), dialOpts...)
+	if err != nil {
+		release()
+		return nil, nil, nil, privateAddressError(ftpError(ctx, err))
+	}
+	user, pass := "anonymous", "anonymous"
+	if u.User != nil {
+		user = u.User.Username()
+		pass, _ = u.User.Password()
+	}
+	if err := c.Login(user, pass); err != nil {
+		c.Quit()
+		release()
+		return nil, nil, nil, fmt.Errorf("ftp login: %w", ftpError(ctx, err))
+	}
+	return u, c, release, nil
+}
+
+// ftpConns отслеживает соединения одной FTP-сессии.
+type ftpConns struct {
+	ctx     context.Context
+	timeout time.Duration
+	control func(string, string, syscall.RawConn) error
+	mu      sync.Mutex
+	conns   []net.Conn
+	closed  bool
+}
+
+func (fc *ftpConns) dial(network, address string) (net.Conn, error) {
+	conn, err := dialTimeout(fc.ctx, network, address, fc.timeout, fc.control)
+	if err != nil {
+		return nil, err
+	}
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.closed {
+		conn.Close()
+		return nil, net.ErrClosed
+	}
+	fc.conns = append(fc.conns, conn)
+	return conn, nil
+}
+
+// close закрывает все соединения сессии; повторные вызовы ничего не делают.
+func (fc *ftpConns) close() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.closed {
+		return
+	}
+	fc.closed = true
+	for _, c := range fc.conns {
+		c.Close()
+	}
+}
+
+// ftpError возвращает причину отмены ctx вместо ошибки закрытого
+// соединения, если операция прервана отменой.
+func ftpError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return err
+}
+
+// ftpDisplayURL возвращает URL без учётных данных для логов и состояния.
+func ftpDisplayURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	return c.String()
+}
@@ -0,0 +1,78 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrHostNotAllowed возвращается, если хост URL запрещён политикой HostPolicy.
+var ErrHostNotAllowed = errors.New("host is not allowed")
+
+// HostPolicy ограничивает хосты, с которых разрешено скачивание. Элемент
+// списка вида "example.com" совпадает только с этим хостом, а вида
+// ".example.com" или "*.example.com" — с самим доменом и всеми его
+// поддоменами. Сравнение нечувствительно к регистру, порт не учитывается.
+// Если Allow не пуст, хост должен совпасть хотя бы с одним его элементом;
+// совпадение с Block запрещает хост в любом случае. Нулевое значение
+// разрешает все хосты.
+type HostPolicy struct {
+	Allow []string
+	Block []string
+}
+
+// Empty сообщает, что политика не накладывает ограничений.
+func (p HostPolicy) Empty() bool {
+	return len(p.Allow) == 0 && len(p.Block) == 0
+}
+
+// CheckURL проверяет хост URL rawURL.
+func (p HostPolicy) CheckURL(rawURL string) error {
+	if p.Empty() {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	return p.CheckHost(u.Hostname())
+}
+
+// CheckHost проверяет хост host (без порта) и возвращает ErrHostNotAllowed,
+// если он запрещён.
+func (p HostPolicy) CheckHost(host string) error {
+	host = normalizeHost(host)
+	if matchHost(p.Block, host) {
+		return fmt.Errorf("%w: %s is blocked", ErrHostNotAllowed, host)
+	}
+	if len(p.Allow) > 0 && !matchHost(p.Allow, host) {
+		return fmt.Errorf("%w: %s is not in the allowlist", ErrHostNotAllowed, host)
+	}
+	return nil
+}
+
+// matchHost сообщает, совпадает ли host с одним из шаблонов patterns.
+func matchHost(patterns []string, host string) bool {
+	for _, p := range patterns {
+		p = normalizeHost(strings.TrimPrefix(p, "*"))
+		if p == "" {
+			continue
+		}
+		if domain, ok := strings.CutPrefix(p, "."); ok {
+			if host == domain || strings.HasSuffix(host, p) {
+				return true
+			}
+			continue
+		}
+		if host == p {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeHost приводит имя хоста к каноническому виду для сравнения.
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
@@ -0,0 +1,106 @@
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// maxFileNameLen ограничивает длину имени, полученного по шаблону: большинство
+// файловых систем не допускают имён длиннее 255 байт.
+const maxFileNameLen = 255
+
+// ErrInvalidFileName возвращается, если шаблон дал имя, которое нельзя
+// использовать: пустое, "." или "..", с суффиксом ".part" или слишком
+// длинное.
+var ErrInvalidFileName = errors.New("invalid file name")
+
+// NameData — переменные шаблона имени файла.
+type NameData struct {
+	TaskID      string // ID задачи
+	Index       int    // номер файла в задаче, с 0
+	URL         string // URL файла без учётных данных
+	URLBasename string // имя, выведенное из URL (см. DeriveFileName)
+	Name        string // URLBasename без расширения
+	Ext         string // расширение URLBasename с точкой или ""
+	Hash        string // первые 12 hex-символов SHA-256 от URL
+	Timestamp   string // время создания задачи в UTC: 20060102-150405
+}
+
+// NewNameData заполняет переменные шаблона для файла index задачи taskID,
+// созданной в created.
+func NewNameData(taskID string, index int, rawURL string, created time.Time) NameData {
+	base := DeriveFileName(rawURL, index)
+	ext := path.Ext(base)
+	sum := sha256.Sum256([]byte(rawURL))
+	return NameData{
+		TaskID:      taskID,
+		Index:       index,
+		URL:         rawURL,
+		URLBasename: base,
+		Name:        strings.TrimSuffix(base, ext),
+		Ext:         ext,
+		Hash:        hex.EncodeToString(sum[:])[:12],
+		Timestamp:   created.UTC().Format("20060102-150405"),
+	}
+}
+
+// NameTemplate — шаблон имени файла в синтаксисе text/template, например
+// "{{.TaskID}}-{{.Index}}{{.Ext}}". Безопасен для параллельного
+// использования.
+type NameTemplate struct {
+	t *template.Template
+}
+
+// ParseNameTemplate разбирает шаблон имени файла. Пустая строка даёт nil —
+// имя выводится из URL, как в DeriveFileName. Шаблон сразу пробуется на
+// примерных данных, чтобы ошибки вроде неизвестной переменной обнаружились
+// при настройке, а не при создании задачи.
+func ParseNameTemplate(s string) (*NameTemplate, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	t, err := template.New("filename").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	nt := &NameTemplate{t: t}
+	sample := NewNameData("id", 0, "https://example.com/file.bin", time.Now())
+	if _, err := nt.render(sample); err != nil {
+		return nil, err
+	}
+	return nt, nil
+}
+
+// Execute возвращает имя файла по шаблону. Разделители путей и NUL в
+// результате заменяются на "_", поэтому имя не выходит за пределы каталога
+// задачи; пустое, "."/".." или слишком длинное имя даёт ErrInvalidFileName.
+func (nt *NameTemplate) Execute(data NameData) (string, error) {
+	name, err := nt.render(data)
+	if err != nil {
+		return "", err
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == 0 {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." || strings.HasSuffix(name, ".part") || len(name) > maxFileNameLen {
+		return "", fmt.Errorf("%w %q from template for file %d", ErrInvalidFileName, name, data.Index)
+	}
+	return name, nil
+}
+
+func (nt *NameTemplate) render(data NameData) (string, error) {
+	var b strings.Builder
+	if err := nt.t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,73 @@
+package download
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseNameTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    string
+		wantNil bool
+		wantErr bool
+	}{
+		{"empty", "", true, false},
+		{"blank", "  ", true, false},
+		{"variables", "{{.TaskID}}-{{.Index}}{{.Ext}}", false, false},
+		{"syntax error", "{{.TaskID", false, true},
+		{"unknown variable", "{{.Missing}}", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nt, err := ParseNameTemplate(tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (nt == nil) != tt.wantNil {
+				t.Errorf("template = %v, want nil %v", nt, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestNameTemplateExecute(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 30, 45, 0, time.FixedZone("MSK", 3*3600))
+	data := NewNameData("abc", 2, "https://example.com/dir/report.tar.gz?x=1", created)
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr error
+	}{
+		{"variables", "{{.TaskID}}-{{.Index}}-{{.Name}}{{.Ext}}", "abc-2-report.tar.gz", nil},
+		{"basename", "{{.URLBasename}}", "report.tar.gz", nil},
+		{"timestamp in UTC", "{{.Timestamp}}{{.Ext}}", "20250301-093045.gz", nil},
+		{"hash", "{{.Hash}}", data.Hash, nil},
+		{"separators replaced", "a/b\\c", "a_b_c", nil},
+		{"traversal stays inside", "../{{.URLBasename}}", ".._report.tar.gz", nil},
+		{"surrounding spaces trimmed", "  {{.TaskID}}  ", "abc", nil},
+		{"empty", "{{if false}}x{{end}}", "", ErrInvalidFileName},
+		{"dot", ".", "", ErrInvalidFileName},
+		{"dot dot", "..", "", ErrInvalidFileName},
+		{"part suffix", "{{.TaskID}}.part", "", ErrInvalidFileName},
+		{"too long", strings.Repeat("a", maxFileNameLen+1), "", ErrInvalidFileName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nt, err := ParseNameTemplate(tt.tmpl)
+			if err != nil {
+				t.Fatalf("ParseNameTemplate: %v", err)
+			}
+			got, err := nt.Execute(data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Execute = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// writePart записывает body во временный файл dest+".part" и атомарно
+// переименовывает его в dest. При offset > 0 body дописывается к первым
+// offset байтам уже имеющегося временного файла (продолжение скачивания),
+// иначе файл создаётся заново. body должен быть ограничен
+// Options.MaxBytes-offset+1 байтами, чтобы превышение лимита было замечено.
+// Если size >= 0, размер файла сверяется с ним (ErrSizeMismatch, в том числе
+// когда соединение оборвалось раньше, чем пришли size байт). finish,
+// если задан, вызывается после чтения тела до переименования и может
+// сообщить об ошибке передачи. idle позволяет отличить таймаут простоя от
+// прочих ошибок чтения. Возвращает размер файла.
+//
+// При любой ошибке временный файл удаляется. Исключение — resumable: если
+// передача оборвалась, полученное начало файла остаётся для продолжения
+// скачивания следующей попыткой (см. Options.Resume).
+func writePart(dest string, body io.Reader, offset, size int64, opts Options, idle *idleReader, resumable bool, finish func() error) (n int64, err error) {
+	tmp := dest + ".part"
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 {
+		flag = os.O_WRONLY
+	}
+	tmpFile, err := os.OpenFile(tmp, flag, 0o666)
+	if err != nil {
+		return 0, err
+	}
+	interrupted := false
+	defer func() {
+		tmpFile.Close()
+		if err != nil && !(resumable && interrupted) {
+			os.Remove(tmp)
+		}
+	}()
+	if offset > 0 {
+		// лишние байты за offset могли остаться от прерванной записи
+		if err := tmpFile.Truncate(offset); err != nil {
+			return 0, err
+		}
+		if _, err := tmpFile.Seek(offset, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+	var dst io.Writer = tmpFile
+	if opts.Progress != nil {
+		dst = progressWriter{w: tmpFile, fn: opts.Progress}
+	}
+	n, err = io.Copy(dst, body)
+	n += offset
+	if err != nil {
+		// ошибка записи на диск — не обрыв передачи
+		var pathErr *os.PathError
+		interrupted = !errors.As(err, &pathErr)
+		if idle != nil && idle.expired() {
+			return n, fmt.Errorf("%w (%s)", ErrIdleTimeout, opts.IdleTimeout)
+		}
+		if size >= 0 && errors.Is(err, io.ErrUnexpectedEOF) {
+			return n, fmt.Errorf("%w: got %d bytes, expected %d: %w", ErrSizeMismatch, n, size, err)
+		}
+		return n, err
+	}
+	if opts.MaxBytes > 0 && n > opts.MaxBytes {
+		return n, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, opts.MaxBytes)
+	}
+	if finish != nil {
+		if err := finish(); err != nil {
+			interrupted = true
+			return n, err
+		}
+	}
+	if size >= 0 && n != size {
+		interrupted = n < size
+		return n, fmt.Errorf("%w: got %d bytes, expected %d", ErrSizeMismatch, n, size)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return n, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return n, err
+	}
+	return n, os.Rename(tmp, dest)
+}
@@ -0,0 +1,77 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+)
+
+// ErrPreflightUnsupported возвращается Preflight, если сервер не поддерживает
+// HEAD (405 Method Not Allowed или 501 Not Implemented).
+var ErrPreflightUnsupported = errors.New("HEAD not supported")
+
+// Info — сведения о файле, полученные до начала передачи тела.
+type Info struct {
+	// Size — размер из Content-Length; -1, если сервер его не сообщил.
+	Size int64
+	// ContentType — значение заголовка Content-Type.
+	ContentType string
+	// Validator — ETag или Last-Modified ответа для заголовка If-Range при
+	// продолжении скачивания (см. Options.ResumeValidator); пусто, если
+	// сервер их не сообщил.
+	Validator string
+	// Resumed — число байт, уже имевшихся во временном файле, с которых
+	// продолжено скачивание. Size при этом — полный размер файла.
+	Resumed int64
+}
+
+// Preflight выполняет HEAD-запрос к fileURL с теми же заголовками,
+// редиректами и ограничениями хостов, что и DownloadWithContext. Если размер
+// известен, сразу применяет проверки Options.MaxBytes и свободного места для
+// dest, чтобы не занимать воркер заведомо неудачной передачей. Ошибки
+// ErrTooLarge и ErrInsufficientDiskSpace означают, что скачивать файл не
+// нужно; прочие ошибки (в том числе ErrPreflightUnsupported) лишь говорят,
+// что сведений получить не удалось, и скачивание можно выполнить обычным GET.
+// Для URL других схем используйте Registry.Preflight.
+func Preflight(ctx context.Context, fileURL, dest string, opts Options) (Info, error) {
+	info := Info{Size: -1}
+	if err := opts.HostPolicy.CheckURL(fileURL); err != nil {
+		return info, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, fileURL, nil)
+	if err != nil {
+		return info, err
+	}
+	setHeaders(req, opts)
+	applyUserinfo(req)
+	release, err := opts.Conns.acquire(ctx)
+	if err != nil {
+		return info, err
+	}
+	defer release()
+	resp, err := client(opts).Do(req)
+	if err != nil {
+		return info, privateAddressError(redactError(err, opts))
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented:
+		return info, fmt.Errorf("%w: %s", ErrPreflightUnsupported, resp.Status)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return info, fmt.Errorf("HEAD: unexpected status %s", resp.Status)
+	}
+	info.Size = resp.ContentLength
+	info.ContentType = resp.Header.Get("Content-Type")
+
+	if opts.MaxBytes > 0 && info.Size > opts.MaxBytes {
+		return info, fmt.Errorf("%w: %d > %d bytes", ErrTooLarge, info.Size, opts.MaxBytes)
+	}
+	if opts.CheckDiskSpace && info.Size > 0 {
+		if err := checkDiskSpace(filepath.Dir(dest), info.Size, opts.MinFreeBytes); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,153 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedScheme возвращается, если для схемы URL не зарегистрирован
+// Downloader.
+var ErrUnsupportedScheme = errors.New("unsupported URL scheme")
+
+// Downloader скачивает файл по URL в dest. Реализация отвечает за запись
+// во временный файл с атомарным переименованием и соблюдает ограничения из
+// opts, которые применимы к её протоколу. Отмена ctx прерывает скачивание.
+type Downloader interface {
+	Download(ctx context.Context, fileURL, dest string, opts Options) (Result, error)
+}
+
+// Preflighter — необязательный интерфейс Downloader, позволяющий узнать
+// размер и тип файла до скачивания (см. Preflight).
+type Preflighter interface {
+	Preflight(ctx context.Context, fileURL, dest string, opts Options) (Info, error)
+}
+
+// DownloaderFunc позволяет использовать функцию как Downloader.
+type DownloaderFunc func(ctx context.Context, fileURL, dest string, opts Options) (Result, error)
+
+// Download вызывает f.
+func (f DownloaderFunc) Download(ctx context.Context, fileURL, dest string, opts Options) (Result, error) {
+	return f(ctx, fileURL, dest, opts)
+}
+
+// Registry сопоставляет схемам URL реализации Downloader. Безопасен для
+// параллельного использования.
+type Registry struct {
+	mu          sync.RWMutex
+	downloaders map[string]Downloader
+}
+
+// NewRegistry возвращает пустой реестр.
+func NewRegistry() *Registry {
+	return &Registry{downloaders: make(map[string]Downloader)}
+}
+
+// DefaultRegistry возвращает реестр со встроенными реализациями: http и
+// https — DownloadWithContext, ftp, ftps и ftpes — DownloadFTP, s3 —
+// DownloadS3.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(httpDownloader{}, "http", "https")
+	r.Register(ftpDownloader{}, "ftp", "ftps", "ftpes")
+	r.Register(s3Downloader{}, "s3")
+	return r
+}
+
+// Register назначает d скачивание URL со схемами schemes, заменяя прежнюю
+// реализацию. Схемы сравниваются без учёта регистра.
+func (r *Registry) Register(d Downloader, schemes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range schemes {
+		r.downloaders[strings.ToLower(s)] = d
+	}
+}
+
+// Lookup возвращает реализацию для схемы scheme.
+func (r *Registry) Lookup(scheme string) (Downloader, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.downloaders[strings.ToLower(scheme)]
+	return d, ok
+}
+
+// Supports сообщает, зарегистрирована ли реализация для схемы scheme.
+func (r *Registry) Supports(scheme string) bool {
+	_, ok := r.Lookup(scheme)
+	return ok
+}
+
+// Download скачивает fileURL в dest реализацией, выбранной по схеме URL.
+// Для незарегистрированной схемы возвращает ErrUnsupportedScheme.
+func (r *Registry) Download(ctx context.Context, fileURL, dest string, opts Options) (Result, error) {
+	d, err := r.downloader(fileURL)
+	if err != nil {
+		return Result{}, err
+	}
+	return d.Download(ctx, fileURL, dest, opts)
+}
+
+// Preflight узнаёт размер и тип файла реализацией, выбранной по схеме URL.
+// Если реализация не поддерживает Preflighter, возвращает
+// ErrPreflightUnsupported.
+func (r *Registry) Preflight(ctx context.Context, fileURL, dest string, opts Options) (Info, error) {
+	d, err := r.downloader(fileURL)
+	if err != nil {
+		return Info{Size: -1}, err
+	}
+	p, ok := d.(Preflighter)
+	if !ok {
+		return Info{Size: -1}, fmt.Errorf("%w: %T", ErrPreflightUnsupported, d)
+	}
+	return p.Preflight(ctx, fileURL, dest, opts)
+}
+
+// downloader возвращает реализацию для схемы fileURL.
+func (r *Registry) downloader(fileURL string) (Downloader, error) {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return nil, err
+	}
+	d, ok := r.Lookup(u.Scheme)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedScheme, u.Scheme)
+	}
+	return d, nil
+}
+
+// httpDownloader скачивает URL http и https.
+type httpDownloader struct{}
+
+func (httpDownloader) Download(ctx context.Context, fileURL, dest string, opts Options) (Result, error) {
+	return DownloadWithContext(ctx, fileURL, dest, opts)
+}
+
+func (httpDownloader) Preflight(ctx context.Context, fileURL, dest string, opts Options) (Info, error) {
+	return Preflight(ctx, fileURL, dest, opts)
+}
+
+// ftpDownloader скачивает URL ftp, ftps и ftpes.
+type ftpDownloader struct{}
+
+func (ftpDownloader) Download(ctx context.Context, fileURL, dest string, opts Options) (Result, error) {
+	return DownloadFTP(ctx, fileURL, dest, opts)
+}
+
+func (ftpDownloader) Preflight(ctx context.Context, fileURL, dest string, opts Options) (Info, error) {
+	return preflightFTP(ctx, fileURL, dest, opts)
+}
+
+// s3Downloader скачивает URL s3.
+type s3Downloader struct{}
+
+func (s3Downloader) Download(ctx context.Context, fileURL, dest string, opts Options) (Result, error) {
+	return DownloadS3(ctx, fileURL, dest, opts)
+}
+
+func (s3Downloader) Preflight(ctx context.Context, fileURL, dest string, opts Options) (Info, error) {
+	return preflightS3(ctx, fileURL, dest, opts)
+}
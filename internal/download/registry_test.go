@@ -0,0 +1,64 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRegistryDispatch(t *testing.T) {
+	var got string
+	fake := func(name string) DownloaderFunc {
+		return func(ctx context.Context, fileURL, dest string, opts Options) (Result, error) {
+			got = name
+			return Result{FinalURL: fileURL}, nil
+		}
+	}
+	r := NewRegistry()
+	r.Register(fake("mem"), "mem")
+	r.Register(fake("blob"), "blob", "BLOBS")
+	r.Register(fake("mem2"), "MEM") // заменяет прежнюю реализацию
+
+	tests := []struct {
+		url     string
+		want    string
+		wantErr error
+	}{
+		{"mem://host/a", "mem2", nil},
+		{"MEM://host/a", "mem2", nil},
+		{"blob://host/a", "blob", nil},
+		{"blobs://host/a", "blob", nil},
+		{"https://host/a", "", ErrUnsupportedScheme},
+		{"host/a", "", ErrUnsupportedScheme},
+	}
+	for _, tt := range tests {
+		got = ""
+		_, err := r.Download(t.Context(), tt.url, "dest", Options{})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Download(%q) error = %v, want %v", tt.url, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("Download(%q) used %q, want %q", tt.url, got, tt.want)
+		}
+	}
+	if _, err := r.Preflight(t.Context(), "mem://host/a", "dest", Options{}); !errors.Is(err, ErrPreflightUnsupported) {
+		t.Errorf("Preflight error = %v, want %v", err, ErrPreflightUnsupported)
+	}
+	if _, err := r.Preflight(t.Context(), "gopher://host/a", "dest", Options{}); !errors.Is(err, ErrUnsupportedScheme) {
+		t.Errorf("Preflight error = %v, want %v", err, ErrUnsupportedScheme)
+	}
+}
+
+func TestDefaultRegistrySchemes(t *testing.T) {
+	r := DefaultRegistry()
+	for _, s := range []string{"http", "https", "HTTPS", "ftp", "ftps", "ftpes", "s3"} {
+		if !r.Supports(s) {
+			t.Errorf("scheme %q not supported", s)
+		}
+	}
+	for _, s := range []string{"", "file", "gopher", "sftp"} {
+		if r.Supports(s) {
+			t.Errorf("scheme %q unexpectedly supported", s)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Config задаёт параметры клиента S3. Учётные данные и регион берутся из
+// стандартной цепочки AWS: переменные окружения (AWS_ACCESS_KEY_ID,
+// AWS_REGION и др.), файлы ~/.aws, роль контейнера или инстанса. Запросы идут
+// HTTP-клиентом AWS SDK: адрес хранилища задаёт администратор, а не автор
+// задачи, поэтому защита от SSRF к нему не применяется, а прокси и
+// сертификаты настраиваются переменными окружения SDK (HTTPS_PROXY,
+// AWS_CA_BUNDLE).
+type S3Config struct {
+	// Endpoint — адрес S3-совместимого хранилища (например, MinIO). Пустое
+	// значение — AWS S3 (или AWS_ENDPOINT_URL_S3 из окружения).
+	Endpoint string
+	// PathStyle включает адресацию вида endpoint/bucket/key вместо
+	// bucket.endpoint/key; нужна большинству S3-совместимых хранилищ.
+	PathStyle bool
+}
+
+// S3Client — клиент S3, создаваемый при первом скачивании: загрузка
+// конфигурации AWS не нужна, пока в задачах нет ссылок s3://. Безопасен для
+// параллельного использования.
+type S3Client struct {
+	cfg    S3Config
+	once   sync.Once
+	client *s3.Client
+	err    error
+}
+
+// NewS3Client возвращает клиент S3 с параметрами cfg.
+func NewS3Client(cfg S3Config) *S3Client {
+	return &S3Client{cfg: cfg}
+}
+
+// defaultS3 используется, если Options.S3 не задан.
+var defaultS3 = NewS3Client(S3Config{})
+
+// get возвращает клиент AWS SDK, загружая конфигурацию при первом вызове.
+func (c *S3Client) get(ctx context.Context) (*s3.Client, error) {
+	c.once.Do(func() {
+		awsCfg, err := config.LoadDefaultConfig(context.WithoutCancel(ctx))
+		if err != nil {
+			c.err = fmt.Errorf("load AWS config: %w", err)
+			return
+		}
+		c.client = s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+			if c.cfg.Endpoint != "" {
+				o.BaseEndpoint = aws.String(c.cfg.Endpoint)
+			}
+			o.UsePathStyle = c.cfg.PathStyle
+		})
+	})
+	return c.client, c.err
+}
+
+// parseS3URL разбирает URL вида s3://bucket/key.
+func parseS3URL(rawURL string) (bucket, key string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	key = strings.TrimPrefix(u.Path, "/")
+	if u.Scheme != "s3" || u.Host == "" || key == "" {
+		return "", "", fmt.Errorf("invalid S3 URL %q: must look like s3://bucket/key", rawURL)
+	}
+	return u.Host, key, nil
+}
+
+// DownloadS3 скачивает объект по URL вида s3://bucket/key клиентом
+// Options.S3 и записывает его в dest так же, как DownloadWithContext: во
+// временный файл с атомарным переименованием. Ограничения хостов применяются
+// к имени бакета. Из opts учитываются также лимит размера, проверка
+// свободного места, таймаут простоя, ожидаемый тип и Progress; заголовки
+// запроса и условные запросы не используются. Отмена ctx прерывает передачу.
+func DownloadS3(ctx context.Context, fileURL, dest string, opts Options) (Result, error) {
+	var res Result
+	if err := opts.HostPolicy.CheckURL(fileURL); err != nil {
+		return res, err
+	}
+	bucket, key, err := parseS3URL(fileURL)
+	if err != nil {
+		return res, err
+	}
+	client, err := s3Client(ctx, opts)
+	if err != nil {
+		return res, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	release, err := opts.Conns.acquire(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer release()
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return res, fmt.Errorf("s3 get %s/%s: %w", bucket, key, err)
+	}
+	defer out.Body.Close()
+	res.FinalURL = fileURL
+	res.ContentType = aws.ToString(out.ContentType)
+	res.ETag = aws.ToString(out.ETag)
+	if out.LastModified != nil {
+		res.LastModified = out.LastModified.UTC().Format(http.TimeFormat)
+	}
+	size := int64(-1)
+	if out.ContentLength != nil {
+		size = *out.ContentLength
+	}
+
+	if opts.OnResponse != nil {
+		opts.OnResponse(Info{Size: size, ContentType: res.ContentType})
+	}
+	if err := checkContentType(res.ContentType, opts.ExpectedContentType, opts.AllowMissingContentType); err != nil {
+		return res, err
+	}
+	if opts.MaxBytes > 0 && size > opts.MaxBytes {
+		return res, fmt.Errorf("%w: %d > %d bytes", ErrTooLarge, size, opts.MaxBytes)
+	}
+	if opts.Reserve != nil {
+		if err := opts.Reserve(size); err != nil {
+			return res, err
+		}
+	}
+	if opts.CheckDiskSpace {
+		if err := checkDiskSpace(filepath.Dir(dest), size, opts.MinFreeBytes); err != nil {
+			return res, err
+		}
+	}
+
+	var body io.Reader = out.Body
+	var idle *idleReader
+	if opts.IdleTimeout > 0 {
+		idle = newIdleReader(out.Body, opts.IdleTimeout, cancel)
+		defer idle.stop()
+		body = idle
+	}
+	if opts.MaxBytes > 0 {
+		body = io.LimitReader(body, opts.MaxBytes+1)
+	}
+	n, err := writePart(dest, body, 0, size, opts, idle, false, nil)
+	if err != nil {
+		return res, err
+	}
+	res.Size = n
+	return res, nil
+}
+
+// preflightS3 узнаёт размер и тип объекта запросом HeadObject. Проверки
+// лимитов те же, что у Preflight.
+func preflightS3(ctx context.Context, fileURL, dest string, opts Options) (Info, error) {
+	info := Info{Size: -1}
+	if err := opts.HostPolicy.CheckURL(fileURL); err != nil {
+		return info, err
+	}
+	bucket, key, err := parseS3URL(fileURL)
+	if err != nil {
+		return info, err
+	}
+	client, err := s3Client(ctx, opts)
+	if err != nil {
+		return info, err
+	}
+	release, err := opts.Conns.acquire(ctx)
+	if err != nil {
+		return info, err
+	}
+	defer release()
+	out, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return info, fmt.Errorf("s3 head %s/%s: %w", bucket, key, err)
+	}
+	if out.ContentLength != nil {
+		info.Size = *out.ContentLength
+	}
+	info.ContentType = aws.ToString(out.ContentType)
+	if opts.MaxBytes > 0 && info.Size > opts.MaxBytes {
+		return info, fmt.Errorf("%w: %d > %d bytes", ErrTooLarge, info.Size, opts.MaxBytes)
+	}
+	if opts.CheckDiskSpace && info.Size > 0 {
+		if err := checkDiskSpace(filepath.Dir(dest), info.Size, opts.MinFreeBytes); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+// s3Client возвращает клиент из Options.S3 или клиент по умолчанию.
+func s3Client(ctx context.Context, opts Options) (*s3.Client, error) {
+	c := opts.S3
+	if c == nil {
+		c = defaultS3
+	}
+	return c.get(ctx)
+}
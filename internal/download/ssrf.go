@@ -0,0 +1,42 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrPrivateAddress возвращается, если хост разрешился в приватный,
+// loopback или link-local адрес, а Options.BlockPrivateIPs включён.
+var ErrPrivateAddress = errors.New("blocked private address")
+
+// denyPrivate — функция Control для net.Dialer, отклоняющая соединения с
+// внутренними адресами.
+func denyPrivate(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+	}
+	if ip := ap.Addr().Unmap(); isPrivate(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, ip)
+	}
+	return nil
+}
+
+// isPrivate сообщает, относится ли адрес к внутренним диапазонам.
+func isPrivate(ip netip.Addr) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// privateAddressError извлекает ErrPrivateAddress из ошибки клиента, чтобы
+// в статусе файла было понятное сообщение, а не вложенная ошибка dial.
+func privateAddressError(err error) error {
+	var opErr *net.OpError
+	if errors.Is(err, ErrPrivateAddress) && errors.As(err, &opErr) {
+		return opErr.Err
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package download
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatusError возвращается, если сервер ответил статусом не из 2xx. Code
+// позволяет отличить временные ошибки (503, 429) от постоянных (404), а
+// RetryAfter — пауза из заголовка Retry-After ответов 429 и 503 (0, если
+// заголовка нет).
+type StatusError struct {
+	Code       int
+	Status     string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return "неправильный статус: " + e.Status
+}
+
+// maxRetryAfterSecs ограничивает Retry-After, чтобы огромное значение не
+// переполнило time.Duration.
+const maxRetryAfterSecs = 24 * 60 * 60
+
+// newStatusError формирует StatusError по ответу resp.
+func newStatusError(resp *http.Response) *StatusError {
+	e := &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		e.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return e
+}
+
+// parseRetryAfter разбирает значение Retry-After: число секунд или дату
+// HTTP. Некорректное или прошедшее значение даёт 0; пауза не длиннее суток.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(min(max(secs, 0), maxRetryAfterSecs)) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return min(t.Sub(now), maxRetryAfterSecs*time.Second)
+	}
+	return 0
+}
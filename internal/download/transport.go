@@ -0,0 +1,246 @@
+package download
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultConnectTimeout — таймаут установки соединения при нулевом
+// TransportConfig.ConnectTimeout, как у http.DefaultTransport.
+const DefaultConnectTimeout = 30 * time.Second
+
+// ErrConnectTimeout возвращается, если соединение с сервером не удалось
+// установить за отведённое время: хост недоступен или не отвечает на
+// подключение.
+var ErrConnectTimeout = errors.New("connect timeout")
+
+// TransportConfig задаёт параметры HTTP-транспорта для запросов к
+// источникам.
+type TransportConfig struct {
+	// Proxy — URL прокси-сервера со схемой http, https или socks5. Пустое
+	// значение — прокси из переменных окружения HTTP_PROXY, HTTPS_PROXY и
+	// NO_PROXY.
+	Proxy string
+	// NoProxy — хосты, к которым при заданном Proxy нужно подключаться
+	// напрямую: "example.com" (вместе с поддоменами), ".example.com" (только
+	// поддомены), IP-адрес, подсеть "10.0.0.0/8" или "*" для всех хостов.
+	NoProxy []string
+	// BlockPrivateIPs запрещает прямые соединения с приватными, loopback и
+	// link-local адресами (см. Options.BlockPrivateIPs).
+	BlockPrivateIPs bool
+	// DecodeContentEncoding разрешает транспорту запрашивать сжатый ответ
+	// (см. Options.DecodeContentEncoding).
+	DecodeContentEncoding bool
+	// Параметры пула соединений (см. http.Transport). Нулевые значения
+	// оставляют настройки http.DefaultTransport.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	// ConnectTimeout ограничивает время установки TCP-соединения, чтобы
+	// недоступные хосты быстро завершались ошибкой ErrConnectTimeout, не
+	// ограничивая длительность самой передачи. 0 — DefaultConnectTimeout.
+	ConnectTimeout time.Duration
+	// RootCAs — корневые сертификаты для проверки серверов (см.
+	// LoadCertPool). nil — системные сертификаты.
+	RootCAs *x509.CertPool
+	// InsecureSkipVerify отключает проверку сертификата сервера. Это
+	// позволяет скачивать с серверов с самоподписанными сертификатами, но
+	// делает соединение уязвимым для перехвата (MITM): любой, кто находится
+	// между сервисом и источником, может подменить скачиваемый файл.
+	// Предпочтительнее добавить сертификат внутреннего CA в RootCAs.
+	InsecureSkipVerify bool
+}
+
+// NewTransport создаёт транспорт по cfg. Транспорт безопасен для
+// параллельного использования и должен переиспользоваться между
+// скачиваниями, чтобы сохранялись открытые соединения.
+//
+// При BlockPrivateIPs адрес проверяется непосредственно перед установкой
+// соединения, уже после разрешения DNS, поэтому проверка действует и для
+// редиректов, и при подмене DNS-ответа между запросами. Соединение с самим
+// прокси-сервером разрешено, даже если он во внутренней сети; адреса
+// источников за прокси разрешает прокси, и для них остаётся только проверка
+// по HostPolicy. Некорректный URL прокси приводит к ошибке каждого запроса.
+func NewTransport(cfg TransportConfig) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DisableCompression = !cfg.DecodeContentEncoding
+	if cfg.MaxIdleConns > 0 {
+		t.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		t.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.RootCAs != nil || cfg.InsecureSkipVerify {
+		t.TLSClientConfig = &tls.Config{
+			RootCAs:            cfg.RootCAs,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		}
+	}
+	if cfg.Proxy != "" {
+		t.Proxy = proxyFunc(cfg.Proxy, cfg.NoProxy)
+	}
+	timeout := cfg.ConnectTimeout
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialTimeout(ctx, network, addr, timeout, nil)
+	}
+	if cfg.BlockPrivateIPs {
+		g := &proxyGuard{proxy: t.Proxy, timeout: timeout}
+		t.Proxy = g.proxyFor
+		t.DialContext = g.dial
+	}
+	return t
+}
+
+// dialTimeout устанавливает соединение с addr не дольше timeout (0 —
+// DefaultConnectTimeout). control, если задан, проверяет адрес перед
+// подключением (см. denyPrivate). Истечение таймаута возвращается как
+// ErrConnectTimeout, чтобы его можно было отличить от прочих ошибок сети.
+func dialTimeout(ctx context.Context, network, addr string, timeout time.Duration, control func(string, string, syscall.RawConn) error) (net.Conn, error) {
+	if timeout <= 0 {
+		timeout = DefaultConnectTimeout
+	}
+	d := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second, Control: control}
+	conn, err := d.DialContext(ctx, network, addr)
+	var netErr net.Error
+	if err != nil && ctx.Err() == nil && errors.As(err, &netErr) && netErr.Timeout() {
+		return nil, fmt.Errorf("%w: %s not reachable within %s", ErrConnectTimeout, addr, timeout)
+	}
+	return conn, err
+}
+
+// proxyFunc возвращает функцию Transport.Proxy для прокси rawURL с
+// исключениями noProxy.
+func proxyFunc(rawURL string, noProxy []string) func(*http.Request) (*url.URL, error) {
+	proxy, err := url.Parse(rawURL)
+	if err == nil {
+		err = validateProxy(proxy)
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		if bypassProxy(req.URL.Hostname(), noProxy) {
+			return nil, nil
+		}
+		return proxy, nil
+	}
+}
+
+// ValidateProxy проверяет URL прокси-сервера.
+func ValidateProxy(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	return validateProxy(u)
+}
+
+func validateProxy(u *url.URL) error {
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("proxy URL %q has no host", u.Redacted())
+	}
+	return nil
+}
+
+// bypassProxy сообщает, нужно ли подключаться к host напрямую.
+func bypassProxy(host string, noProxy []string) bool {
+	host = normalizeHost(host)
+	ip, ipErr := netip.ParseAddr(host)
+	for _, p := range noProxy {
+		p = normalizeHost(p)
+		switch {
+		case p == "":
+		case p == "*":
+			return true
+		case strings.Contains(p, "/"):
+			if prefix, err := netip.ParsePrefix(p); err == nil && ipErr == nil && prefix.Contains(ip) {
+				return true
+			}
+		case strings.HasPrefix(p, "."):
+			if strings.HasSuffix(host, p) {
+				return true
+			}
+		case host == p || strings.HasSuffix(host, "."+p):
+			return true
+		}
+	}
+	return false
+}
+
+// proxyGuard пропускает соединения с прокси-серверами, которые вернула
+// функция proxy, а все остальные соединения проверяет denyPrivate.
+type proxyGuard struct {
+	proxy   func(*http.Request) (*url.URL, error)
+	timeout time.Duration // таймаут установки соединения
+	allowed sync.Map      // адрес host:port прокси -> struct{}
+}
+
+func (g *proxyGuard) proxyFor(req *http.Request) (*url.URL, error) {
+	if g.proxy == nil {
+		return nil, nil
+	}
+	u, err := g.proxy(req)
+	if u != nil {
+		g.allowed.Store(proxyAddr(u), struct{}{})
+	}
+	return u, err
+}
+
+func (g *proxyGuard) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if _, ok := g.allowed.Load(addr); ok {
+		return dialTimeout(ctx, network, addr, g.timeout, nil)
+	}
+	return dialTimeout(ctx, network, addr, g.timeout, denyPrivate)
+}
+
+// proxyAddr возвращает адрес host:port, по которому транспорт подключается
+// к прокси u, подставляя порт по умолчанию для схемы.
+func proxyAddr(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := map[string]string{"http": "80", "https": "443", "socks5": "1080", "socks5h": "1080"}[u.Scheme]
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// LoadCertPool возвращает системные корневые сертификаты, дополненные
+// сертификатами из PEM-файла path, например внутреннего CA. Если системные
+// сертификаты недоступны, пул содержит только сертификаты из файла.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,28 @@
+package download
+
+import "net/http"
+
+// applyUserinfo переносит учётные данные из URL запроса в заголовок
+// Authorization (Basic) и убирает их из URL, чтобы они не попали в ошибки и
+// Result.FinalURL. Явно заданный заголовок Authorization не перезаписывается.
+func applyUserinfo(req *http.Request) {
+	user := req.URL.User
+	if user == nil {
+		return
+	}
+	req.URL.User = nil
+	if req.Header.Get("Authorization") != "" {
+		return
+	}
+	pass, _ := user.Password()
+	req.SetBasicAuth(user.Username(), pass)
+}
+
+// redactError скрывает секреты в URL ошибки запроса, если задан
+// Options.Redactor.
+func redactError(err error, opts Options) error {
+	if opts.Redactor == nil {
+		return err
+	}
+	return opts.Redactor.Error(err)
+}
@@ -0,0 +1,223 @@
+// Протокол gRPC сервиса загрузки файлов. Сервер — internal/grpcapi.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: downloader/v1/downloader.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Downloader_CreateTask_FullMethodName = "/downloader.v1.Downloader/CreateTask"
+	Downloader_GetTask_FullMethodName    = "/downloader.v1.Downloader/GetTask"
+	Downloader_WatchTask_FullMethodName  = "/downloader.v1.Downloader/WatchTask"
+)
+
+// DownloaderClient is the client API for Downloader service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Downloader создаёт задачи скачивания и сообщает их состояние. Методы
+// соответствуют POST /tasks, GET /tasks/{id} и GET /tasks/{id}/events HTTP API.
+type DownloaderClient interface {
+	// CreateTask создаёт задачу. Ошибки параметров — INVALID_ARGUMENT,
+	// повторное использование ключа идемпотентности с другими URL —
+	// ALREADY_EXISTS.
+	CreateTask(ctx context.Context, in *CreateTaskRequest, opts ...grpc.CallOption) (*CreateTaskResponse, error)
+	// GetTask возвращает состояние задачи или NOT_FOUND.
+	GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*Task, error)
+	// WatchTask сразу отправляет состояние задачи, затем — после каждого
+	// изменения. Поток завершается, когда задача переходит в терминальное
+	// состояние.
+	WatchTask(ctx context.Context, in *WatchTaskRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Task], error)
+}
+
+type downloaderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDownloaderClient(cc grpc.ClientConnInterface) DownloaderClient {
+	return &downloaderClient{cc}
+}
+
+func (c *downloaderClient) CreateTask(ctx context.Context, in *CreateTaskRequest, opts ...grpc.CallOption) (*CreateTaskResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateTaskResponse)
+	err := c.cc.Invoke(ctx, Downloader_CreateTask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *downloaderClient) GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*Task, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Task)
+	err := c.cc.Invoke(ctx, Downloader_GetTask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *downloaderClient) WatchTask(ctx context.Context, in *WatchTaskRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Task], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Downloader_ServiceDesc.Streams[0], Downloader_WatchTask_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchTaskRequest, Task]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Downloader_WatchTaskClient = grpc.ServerStreamingClient[Task]
+
+// DownloaderServer is the server API for Downloader service.
+// All implementations must embed UnimplementedDownloaderServer
+// for forward compatibility.
+//
+// Downloader создаёт задачи скачивания и сообщает их состояние. Методы
+// соответствуют POST /tasks, GET /tasks/{id} и GET /tasks/{id}/events HTTP API.
+type DownloaderServer interface {
+	// CreateTask создаёт задачу. Ошибки параметров — INVALID_ARGUMENT,
+	// повторное использование ключа идемпотентности с другими URL —
+	// ALREADY_EXISTS.
+	CreateTask(context.Context, *CreateTaskRequest) (*CreateTaskResponse, error)
+	// GetTask возвращает состояние задачи или NOT_FOUND.
+	GetTask(context.Context, *GetTaskRequest) (*Task, error)
+	// WatchTask сразу отправляет состояние задачи, затем — после каждого
+	// изменения. Поток завершается, когда задача переходит в терминальное
+	// состояние.
+	WatchTask(*WatchTaskRequest, grpc.ServerStreamingServer[Task]) error
+	mustEmbedUnimplementedDownloaderServer()
+}
+
+// UnimplementedDownloaderServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDownloaderServer struct{}
+
+func (UnimplementedDownloaderServer) CreateTask(context.Context, *CreateTaskRequest) (*CreateTaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTask not implemented")
+}
+func (UnimplementedDownloaderServer) GetTask(context.Context, *GetTaskRequest) (*Task, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTask not implemented")
+}
+func (UnimplementedDownloaderServer) WatchTask(*WatchTaskRequest, grpc.ServerStreamingServer[Task]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchTask not implemented")
+}
+func (UnimplementedDownloaderServer) mustEmbedUnimplementedDownloaderServer() {}
+func (UnimplementedDownloaderServer) testEmbeddedByValue()                    {}
+
+// UnsafeDownloaderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DownloaderServer will
+// result in compilation errors.
+type UnsafeDownloaderServer interface {
+	mustEmbedUnimplementedDownloaderServer()
+}
+
+func RegisterDownloaderServer(s grpc.ServiceRegistrar, srv DownloaderServer) {
+	// If the following call pancis, it indicates UnimplementedDownloaderServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Downloader_ServiceDesc, srv)
+}
+
+func _Downloader_CreateTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DownloaderServer).CreateTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Downloader_CreateTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DownloaderServer).CreateTask(ctx, req.(*CreateTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Downloader_GetTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DownloaderServer).GetTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Downloader_GetTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DownloaderServer).GetTask(ctx, req.(*GetTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Downloader_WatchTask_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchTaskRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DownloaderServer).WatchTask(m, &grpc.GenericServerStream[WatchTaskRequest, Task]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Downloader_WatchTaskServer = grpc.ServerStreamingServer[Task]
+
+// Downloader_ServiceDesc is the grpc.ServiceDesc for Downloader service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Downloader_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "downloader.v1.Downloader",
+	HandlerType: (*DownloaderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateTask",
+			Handler:    _Downloader_CreateTask_Handler,
+		},
+		{
+			MethodName: "GetTask",
+			Handler:    _Downloader_GetTask_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchTask",
+			Handler:       _Downloader_WatchTask_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "downloader/v1/downloader.proto",
+}
@@ -0,0 +1,195 @@
+// Package grpcapi реализует gRPC API сервиса (proto/downloader/v1) поверх
+// того же менеджера задач, что и HTTP API.
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=hh03012025 --go-grpc_out=../.. --go-grpc_opt=module=hh03012025 downloader/v1/downloader.proto
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"hh03012025/internal/api"
+	"hh03012025/internal/grpcapi/pb"
+	"hh03012025/internal/manager"
+	"hh03012025/internal/model"
+	"hh03012025/internal/util"
+)
+
+// Server реализует сервис pb.DownloaderServer.
+type Server struct {
+	pb.UnimplementedDownloaderServer
+	m *manager.Manager
+}
+
+// NewServer создаёт gRPC-сервер с сервисом Downloader поверх m. Если keys не
+// пуст, каждый вызов должен передавать один из ключей в метаданных
+// "authorization: Bearer <key>" или "x-api-key", как и в HTTP API; иначе
+// возвращается UNAUTHENTICATED.
+func NewServer(m *manager.Manager, keys []string) *grpc.Server {
+	var opts []grpc.ServerOption
+	if len(keys) > 0 {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				if err := checkKey(ctx, keys); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := checkKey(ss.Context(), keys); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}))
+	}
+	s := grpc.NewServer(opts...)
+	pb.RegisterDownloaderServer(s, &Server{m: m})
+	return s
+}
+
+// checkKey проверяет ключ доступа из метаданных вызова.
+func checkKey(ctx context.Context, keys []string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var key string
+	if v := md.Get("authorization"); len(v) > 0 {
+		if scheme, token, ok := strings.Cut(v[0], " "); ok && strings.EqualFold(scheme, "Bearer") {
+			key = strings.TrimSpace(token)
+		}
+	}
+	if v := md.Get("x-api-key"); key == "" && len(v) > 0 {
+		key = strings.TrimSpace(v[0])
+	}
+	if !api.ValidAPIKey(key, keys) {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return nil
+}
+
+// CreateTask создаёт задачу, как POST /tasks.
+func (s *Server) CreateTask(_ context.Context, req *pb.CreateTaskRequest) (*pb.CreateTaskResponse, error) {
+	spec := manager.TaskSpec{
+		IdempotencyKey:   strings.TrimSpace(req.GetIdempotencyKey()),
+		CallbackURL:      strings.TrimSpace(req.GetCallbackUrl()),
+		FileCallbackURL:  strings.TrimSpace(req.GetFileCallbackUrl()),
+		FilenameTemplate: req.GetFilenameTemplate(),
+		Priority:         strings.TrimSpace(req.GetPriority()),
+		Extract:          req.GetExtract(),
+		MaxConcurrent:    int(req.GetMaxConcurrent()),
+		DestSubdir:       req.GetDestSubdir(),
+		Labels:           req.GetLabels(),
+	}
+	if req.GetStartAt() != nil {
+		spec.StartAt = req.GetStartAt().AsTime()
+	}
+	for _, f := range req.GetFiles() {
+		u := strings.TrimSpace(f.GetUrl())
+		if u == "" {
+			continue
+		}
+		spec.Files = append(spec.Files, manager.FileSpec{
+			URL:                 u,
+			Headers:             f.GetHeaders(),
+			Mirrors:             f.GetMirrors(),
+			Accept:              strings.TrimSpace(f.GetAccept()),
+			ExpectedContentType: strings.TrimSpace(f.GetExpectedContentType()),
+			SHA256:              strings.TrimSpace(f.GetSha256()),
+			Filename:            strings.TrimSpace(f.GetFilename()),
+		})
+	}
+	task, created, err := s.m.AddTask(spec)
+	switch {
+	case errors.Is(err, manager.ErrIdempotencyConflict):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, manager.ErrTooManyTasks):
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, manager.ErrDuplicateTask):
+	case err != nil:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.CreateTaskResponse{TaskId: task.ID, Status: string(task.Status), Created: created}, nil
+}
+
+// GetTask возвращает состояние задачи, как GET /tasks/{id}.
+func (s *Server) GetTask(_ context.Context, req *pb.GetTaskRequest) (*pb.Task, error) {
+	if !util.ValidID(req.GetId()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid task ID")
+	}
+	task, ok := s.m.GetTask(req.GetId())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "task not found")
+	}
+	return taskProto(task), nil
+}
+
+// WatchTask отправляет состояние задачи после каждого изменения, используя
+// ту же подписку, что и GET /tasks/{id}/events.
+func (s *Server) WatchTask(req *pb.WatchTaskRequest, stream grpc.ServerStreamingServer[pb.Task]) error {
+	id := req.GetId()
+	if !util.ValidID(id) {
+		return status.Error(codes.InvalidArgument, "invalid task ID")
+	}
+	updates, unsubscribe, ok := s.m.Subscribe(id)
+	if !ok {
+		return status.Error(codes.NotFound, "task not found")
+	}
+	defer unsubscribe()
+	for {
+		task, ok := s.m.GetTask(id)
+		if !ok {
+			return status.Error(codes.NotFound, "task deleted")
+		}
+		if err := stream.Send(taskProto(task)); err != nil {
+			return err
+		}
+		if manager.IsTerminal(task.Status) {
+			return nil
+		}
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-updates:
+		}
+	}
+}
+
+// taskProto переводит задачу в сообщение pb.Task.
+func taskProto(t *model.Task) *pb.Task {
+	p := &pb.Task{
+		Id:        t.ID,
+		Status:    string(t.Status),
+		Priority:  t.Priority,
+		Total:     int32(len(t.Files)),
+		Labels:    t.Labels,
+		CreatedAt: timestamppb.New(t.CreatedAt),
+		UpdatedAt: timestamppb.New(t.UpdatedAt),
+	}
+	for _, f := range t.Files {
+		if f.Status == model.StatusCompleted {
+			p.Completed++
+		}
+		p.Files = append(p.Files, &pb.File{
+			Url:             f.URL,
+			Status:          string(f.Status),
+			Error:           f.Error,
+			Filename:        f.Filename,
+			Path:            f.Path,
+			Size:            f.Size,
+			DownloadedBytes: f.Downloaded,
+			ContentType:     f.ContentType,
+			SpeedBps:        f.Speed,
+			EtaSeconds:      f.ETA,
+			RetryCount:      int32(f.RetryCount),
+			SourceUrl:       f.SourceURL,
+			FinalUrl:        f.FinalURL,
+			Sha256:          f.SHA256,
+		})
+	}
+	return p
+}
@@ -0,0 +1,72 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrChecksumMismatch возвращается, если SHA-256 скачанного файла не совпал
+// с ожидаемым (FileSpec.SHA256).
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// checkFileSpecs проверяет параметры отдельных файлов: формат контрольной
+// суммы, заголовок Accept и имена файлов, которые должны быть простыми и не
+// повторяться.
+func checkFileSpecs(specs []FileSpec) error {
+	names := make(map[string]bool)
+	for _, s := range specs {
+		if s.SHA256 != "" && !validSHA256(s.SHA256) {
+			return fmt.Errorf("invalid sha256 %q for %s: must be 64 hex characters", s.SHA256, s.URL)
+		}
+		if strings.ContainsAny(s.Accept, "\r\n\x00") {
+			return fmt.Errorf("invalid accept %q for %s", s.Accept, s.URL)
+		}
+		if s.Filename == "" {
+			continue
+		}
+		if s.Filename == "." || s.Filename == ".." || strings.ContainsAny(s.Filename, `/\`) ||
+			strings.HasSuffix(s.Filename, ".part") {
+			return fmt.Errorf("invalid filename %q for %s", s.Filename, s.URL)
+		}
+		key := strings.ToLower(s.Filename)
+		if names[key] {
+			return fmt.Errorf("duplicate filename %q", s.Filename)
+		}
+		names[key] = true
+	}
+	return nil
+}
+
+// validSHA256 сообщает, что s — SHA-256 в hex.
+func validSHA256(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == sha256.Size
+}
+
+// verifyChecksum сверяет SHA-256 файла path с want. При несовпадении файл
+// удаляется, чтобы повторное скачивание не приняло его за уже скачанный.
+func verifyChecksum(path, want string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	got := hex.EncodeToString(h.Sum(nil))
+	if got == want {
+		return nil
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: got sha256 %s, expected %s", ErrChecksumMismatch, got, want)
+}
@@ -0,0 +1,258 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"hh03012025/internal/model"
+)
+
+// ErrTaskPaused — причина отмены контекста задачи при её приостановке.
+// Прерванные паузой файлы возвращаются в статус "pending".
+var ErrTaskPaused = errors.New("task paused")
+
+// ErrTaskNotFound возвращается, если задача с указанным ID неизвестна.
+var ErrTaskNotFound = errors.New("task not found")
+
+// ErrTaskFinished возвращается при попытке управлять уже завершённой задачей.
+var ErrTaskFinished = errors.New("task already finished")
+
+// PauseTask приостанавливает задачу: активные скачивания прерываются и
+// возвращаются в "pending", новые файлы задачи не скачиваются до вызова
+// ResumeTask. Статус задачи становится "paused" и сохраняется между
+// перезапусками. Повторная пауза ничего не меняет.
+func (m *Manager) PauseTask(id string) (*model.Task, error) {
+	m.mu.Lock()
+	task, ok := m.tasks[id]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrTaskNotFound
+	}
+	if IsTerminal(task.Status) {
+		m.mu.Unlock()
+		return nil, ErrTaskFinished
+	}
+	if !task.Paused {
+		task.Paused = true
+		m.controls[id].cancel(ErrTaskPaused)
+		task.UpdatedAt = time.Now().UTC()
+		m.recomputeStatus(task)
+		m.notify(id)
+	}
+	c := copyTask(task)
+	m.mu.Unlock()
+	m.persistTask(id)
+	return c, nil
+}
+
+// ResumeTask снимает задачу с паузы и ставит её незавершённые файлы обратно
+// в очередь. Для задачи, не находящейся на паузе, ничего не делает.
+func (m *Manager) ResumeTask(id string) (*model.Task, error) {
+	m.mu.Lock()
+	task, ok := m.tasks[id]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrTaskNotFound
+	}
+	if !task.Paused {
+		c := copyTask(task)
+		m.mu.Unlock()
+		return c, nil
+	}
+	task.Paused = false
+	// контекст приостановленной задачи отменён, нужен новый
+	m.newTaskControl(id)
+	var pending []int
+	for idx, f := range task.Files {
+		// файлы отложенной задачи поставит в очередь ScheduleLoop
+		if f.Status == model.StatusPending && task.StartAt == nil {
+			pending = append(pending, idx)
+		}
+	}
+	task.UpdatedAt = time.Now().UTC()
+	m.recomputeStatus(task)
+	m.notify(id)
+	m.mu.Unlock()
+	for _, idx := range pending {
+		m.enqueueJob(id, idx)
+	}
+	m.persistTask(id)
+	c, _ := m.GetTask(id)
+	return c, nil
+}
+
+// CancelTask останавливает задачу, не удаляя её: активные скачивания
+// прерываются, а все ещё не скачанные файлы, включая скачиваемые, сразу
+// получают статус "canceled" и возвращаются так уже в ответе. Скачанные
+// файлы и запись о задаче сохраняются, а отменённые файлы не ставятся в
+// очередь и после перезапуска. Отмена задачи на паузе или ждущей отложенного
+// запуска тоже завершает её. Повторная отмена ничего не меняет; для задачи,
+// завершившейся иначе, возвращается ErrTaskFinished.
+func (m *Manager) CancelTask(id string) (*model.Task, error) {
+	m.mu.Lock()
+	task, ok := m.tasks[id]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrTaskNotFound
+	}
+	if task.Status == model.StatusCanceled {
+		c := copyTask(task)
+		m.mu.Unlock()
+		return c, nil
+	}
+	if IsTerminal(task.Status) {
+		m.mu.Unlock()
+		return nil, ErrTaskFinished
+	}
+	finished, done := m.abortTask(task, ErrTaskCanceled, model.StatusCanceled)
+	c := copyTask(task)
+	m.mu.Unlock()
+	slog.Info("task canceled", "task_id", id)
+	m.persistTask(id)
+	if finished != nil {
+		go m.notifyCompletion(finished)
+	}
+	if done {
+		m.evictTasks()
+	}
+	return c, nil
+}
+
+// abortTask прерывает незавершённую задачу task с причиной cause: активные
+// скачивания останавливаются, а все незавершённые файлы, в том числе
+// скачиваемые сейчас, сразу получают статус status. Итог прерванного
+// скачивания, о котором воркер сообщит позже, уже не применяется (см.
+// updateFileState), поэтому уведомление о файле отправляется один раз.
+// Возвращает копию задачи для уведомления о завершении (nil, если
+// оно не нужно) и признак перехода задачи в терминальное состояние.
+// Вызывать под m.mu.
+func (m *Manager) abortTask(task *model.Task, cause error, status model.Status) (finished *model.Task, done bool) {
+	// контекст приостановленной задачи уже отменён паузой; новый контекст с
+	// причиной отмены не даст requeuePaused вернуть прерванные файлы в очередь
+	if m.controls[task.ID].ctx.Err() != nil {
+		m.newTaskControl(task.ID)
+	}
+	m.controls[task.ID].cancel(cause)
+	task.Paused = false
+	for idx := range task.Files {
+		switch task.Files[idx].Status {
+		case model.StatusInProgress:
+			m.stopSpeed(Job{TaskID: task.ID, FileIndex: idx}, &task.Files[idx])
+		case model.StatusPending:
+		default:
+			continue
+		}
+		task.Files[idx].Status = status
+		task.Files[idx].Error = cause.Error()
+		m.notifyFile(task, idx)
+	}
+	task.UpdatedAt = time.Now().UTC()
+	prev := task.Status
+	m.recomputeStatus(task)
+	m.notify(task.ID)
+	done = IsTerminal(task.Status) && !IsTerminal(prev)
+	if done {
+		m.endTaskSpan(task)
+	}
+	if done && task.CallbackURL != "" {
+		finished = copyTask(task)
+	}
+	return finished, done
+}
+
+// ErrNoFailedFiles возвращается RetryTask, если в задаче нет файлов с ошибкой.
+var ErrNoFailedFiles = errors.New("task has no failed files")
+
+// RetryTask повторно ставит в очередь файлы задачи со статусом "error",
+// сбрасывая их ошибку; уже скачанные файлы не трогаются. Файлы задачи на
+// паузе или ждущей отложенного запуска лишь возвращаются в "pending" и ждут
+// ResumeTask или StartAt. Файлы, секреты которых утеряны при перезапуске,
+// не повторяются. Возвращает ErrNoFailedFiles, если повторять нечего, или
+// ErrSecretsLost, если все файлы с ошибкой — такие.
+func (m *Manager) RetryTask(id string) (*model.Task, error) {
+	m.mu.Lock()
+	task, ok := m.tasks[id]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrTaskNotFound
+	}
+	var failed []int
+	lost := false
+	for idx, f := range task.Files {
+		if f.Status == model.StatusError && secretsLost(f) {
+			lost = true
+			continue
+		}
+		if f.Status == model.StatusError {
+			task.Files[idx].Status = model.StatusPending
+			task.Files[idx].Error = ""
+			failed = append(failed, idx)
+		}
+	}
+	if len(failed) == 0 {
+		m.mu.Unlock()
+		if lost {
+			return nil, ErrSecretsLost
+		}
+		return nil, ErrNoFailedFiles
+	}
+	// истёкший срок не должен сразу снова прервать повторные скачивания
+	if task.Deadline != nil && !time.Now().Before(*task.Deadline) {
+		task.Deadline = nil
+	}
+	// контекст отменённой задачи уже не годится для новых скачиваний
+	if !task.Paused && m.controls[id].ctx.Err() != nil {
+		m.newTaskControl(id)
+	}
+	task.UpdatedAt = time.Now().UTC()
+	m.recomputeStatus(task)
+	m.notify(id)
+	enqueue := !task.Paused && !m.draining && task.StartAt == nil
+	m.mu.Unlock()
+	slog.Info("task retry", "task_id", id, "files", len(failed))
+	if enqueue {
+		for _, idx := range failed {
+			m.enqueueJob(id, idx)
+		}
+	}
+	m.persistTask(id)
+	c, _ := m.GetTask(id)
+	return c, nil
+}
+
+// requeuePaused возвращает файл, прерванный паузой, в статус "pending". Если
+// задачу уже успели возобновить, файл сразу ставится в очередь повторно, а
+// если отменить — получает статус "canceled".
+func (m *Manager) requeuePaused(taskID string, index int) {
+	m.mu.Lock()
+	task, ok := m.tasks[taskID]
+	if !ok || index < 0 || index >= len(task.Files) {
+		m.mu.Unlock()
+		return
+	}
+	if ctx := m.controls[taskID].ctx; !pausedBy(ctx) && ctx.Err() != nil {
+		m.mu.Unlock()
+		m.updateFileState(taskID, index, abortStatus(ctx), context.Cause(ctx).Error())
+		return
+	}
+	task.Files[index].Status = model.StatusPending
+	task.Files[index].Error = ""
+	task.UpdatedAt = time.Now().UTC()
+	m.recomputeStatus(task)
+	m.notify(taskID)
+	resumed := !task.Paused
+	m.mu.Unlock()
+	m.persistTask(taskID)
+	if resumed {
+		// вызывается из воркера: при заполненной очереди синхронная
+		// постановка заблокировала бы одного из её потребителей
+		go m.enqueueJob(taskID, index)
+	}
+}
+
+// pausedBy сообщает, отменён ли контекст задачи паузой.
+func pausedBy(taskCtx context.Context) bool {
+	return errors.Is(context.Cause(taskCtx), ErrTaskPaused)
+}
@@ -0,0 +1,95 @@
+package manager
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"hh03012025/internal/model"
+)
+
+func TestCancelTaskMarksActiveFilesCanceled(t *testing.T) {
+	src := &blockingSource{release: make(chan struct{})}
+	srv := httptest.NewServer(src)
+	defer srv.Close()
+	defer close(src.release)
+
+	var mu sync.Mutex
+	var callbacks []fileCallbackPayload
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p fileCallbackPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err == nil {
+			mu.Lock()
+			callbacks = append(callbacks, p)
+			mu.Unlock()
+		}
+	}))
+	defer hook.Close()
+
+	m := newTestManager(t, 100, Config{AllowPrivateIPs: true}, nil)
+	startWorkers(t, m, 1)
+	task, _, err := m.AddTask(TaskSpec{
+		Files:           []FileSpec{{URL: srv.URL + "/active"}, {URL: srv.URL + "/queued"}},
+		FileCallbackURL: hook.URL,
+	})
+	if err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+	src.waitInflight(t, 1)
+
+	got, err := m.CancelTask(task.ID)
+	if err != nil {
+		t.Fatalf("CancelTask: %v", err)
+	}
+	if got.Status != model.StatusCanceled {
+		t.Errorf("task status = %q, want canceled", got.Status)
+	}
+	for i, f := range got.Files {
+		if f.Status != model.StatusCanceled || f.Error != ErrTaskCanceled.Error() {
+			t.Errorf("file %d in response: status %q, error %q; want canceled", i, f.Status, f.Error)
+		}
+	}
+
+	// воркер сообщает об итоге прерванного скачивания позже: он не должен
+	// ни изменить статус, ни отправить второе уведомление о файле
+	src.waitInflight(t, 0)
+	m.mu.RLock()
+	for len(m.active) > 0 {
+		m.mu.RUnlock()
+		time.Sleep(time.Millisecond)
+		m.mu.RLock()
+	}
+	m.mu.RUnlock()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(callbacks)
+		mu.Unlock()
+		if n >= len(got.Files) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	seen := make(map[int]int)
+	for _, p := range callbacks {
+		seen[p.FileIndex]++
+		if p.Status != model.StatusCanceled {
+			t.Errorf("file %d callback status %q, want canceled", p.FileIndex, p.Status)
+		}
+	}
+	if len(seen) != 2 || seen[0] != 1 || seen[1] != 1 {
+		t.Errorf("file callbacks per index = %v, want one for each of 2 files", seen)
+	}
+	after, _ := m.GetTask(task.ID)
+	for i, f := range after.Files {
+		if f.Status != model.StatusCanceled {
+			t.Errorf("file %d after worker finished: status %q, want canceled", i, f.Status)
+		}
+	}
+}
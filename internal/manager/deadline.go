@@ -0,0 +1,82 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"hh03012025/internal/model"
+)
+
+// ErrDeadlineExceeded — причина отмены контекста задачи по истечении её
+// срока (TaskSpec.Deadline или MaxDuration). Незавершённые файлы получают
+// статус "error".
+var ErrDeadlineExceeded = errors.New("task deadline exceeded")
+
+// ErrDeadlineInPast возвращается AddTask, если срок задачи уже прошёл.
+var ErrDeadlineInPast = errors.New("deadline is in the past")
+
+// taskDeadline возвращает срок задачи по spec: MaxDuration отсчитывается от
+// начала выполнения — момента now или отложенного запуска startAt. nil —
+// срок не задан.
+func taskDeadline(spec TaskSpec, now time.Time, startAt *time.Time) *time.Time {
+	switch {
+	case spec.MaxDuration > 0:
+		from := now
+		if startAt != nil {
+			from = *startAt
+		}
+		d := from.Add(spec.MaxDuration)
+		return &d
+	case !spec.Deadline.IsZero():
+		d := spec.Deadline.UTC()
+		return &d
+	}
+	return nil
+}
+
+// armDeadline запускает таймер, завершающий задачу t по истечении её срока.
+// Уже прошедший срок (например, после перезапуска сервиса) срабатывает
+// сразу. Вызывать под m.mu.
+func (m *Manager) armDeadline(t *model.Task) {
+	if t.Deadline == nil || IsTerminal(t.Status) {
+		return
+	}
+	id := t.ID
+	time.AfterFunc(time.Until(*t.Deadline), func() { m.expireTask(id) })
+}
+
+// expireTask завершает задачу id с истёкшим сроком: активные скачивания
+// прерываются, а ещё не скачанные файлы получают статус "error". Задача,
+// которая уже завершена, удалена или срок которой снят повторным запуском,
+// не меняется.
+func (m *Manager) expireTask(id string) {
+	m.mu.Lock()
+	task, ok := m.tasks[id]
+	if !ok || IsTerminal(task.Status) || task.Deadline == nil || time.Now().Before(*task.Deadline) {
+		m.mu.Unlock()
+		return
+	}
+	deadline := *task.Deadline
+	finished, done := m.abortTask(task, ErrDeadlineExceeded, model.StatusError)
+	m.mu.Unlock()
+	slog.Info("task deadline exceeded", "task_id", id, "deadline", deadline)
+	m.persistTask(id)
+	if finished != nil {
+		go m.notifyCompletion(finished)
+	}
+	if done {
+		m.evictTasks()
+	}
+}
+
+// abortStatus возвращает статус файла, скачивание которого прервано отменой
+// контекста задачи ctx: "error" по истечении срока задачи, иначе
+// "canceled".
+func abortStatus(ctx context.Context) model.Status {
+	if errors.Is(context.Cause(ctx), ErrDeadlineExceeded) {
+		return model.StatusError
+	}
+	return model.StatusCanceled
+}
@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+
+	"hh03012025/internal/model"
+)
+
+// ErrDuplicateTask возвращается AddTask вместе с уже существующей задачей,
+// если включён Config.DedupTasks и незавершённая задача с тем же набором
+// URL уже есть. Новая задача при этом не создаётся.
+var ErrDuplicateTask = errors.New("task with the same URLs is already in progress")
+
+// urlSetHash возвращает хеш отсортированного списка URL файлов, не
+// зависящий от порядка URL в запросе.
+func urlSetHash(files []model.FileState) string {
+	urls := make([]string, len(files))
+	for i, f := range files {
+		urls[i] = f.URL
+	}
+	slices.Sort(urls)
+	h := sha256.New()
+	for _, u := range urls {
+		h.Write([]byte(u))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lookupDuplicate возвращает незавершённую задачу с хешем набора URL hash
+// или nil. Индекс не очищается при завершении задач: завершённая задача
+// просто не считается дубликатом. Вызывается под m.mu.
+func (m *Manager) lookupDuplicate(hash string) *model.Task {
+	id, ok := m.urlIndex[hash]
+	if !ok {
+		return nil
+	}
+	t, ok := m.tasks[id]
+	if !ok || IsTerminal(t.Status) {
+		return nil
+	}
+	return t
+}
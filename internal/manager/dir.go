@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrDownloadDirNotWritable возвращается CheckDownloadDir, если каталог
+// загрузок нельзя создать или записать в него файл.
+var ErrDownloadDirNotWritable = errors.New("download directory is not writable")
+
+// CheckDownloadDir создаёт каталог загрузок, если его нет, и проверяет право
+// записи пробным файлом, который сразу удаляется. Вызывается при запуске:
+// каталог без прав записи или на смонтированном только для чтения диске
+// иначе обнаружился бы лишь по ошибкам каждого скачивания.
+func (m *Manager) CheckDownloadDir() error {
+	dir := m.cfg.DownloadDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("%w: %v", ErrDownloadDirNotWritable, err)
+	}
+	f, err := os.CreateTemp(dir, ".write-probe-*")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDownloadDirNotWritable, err)
+	}
+	_, err = f.Write([]byte("probe"))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	os.Remove(f.Name())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDownloadDirNotWritable, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hh03012025/internal/model"
+)
+
+func TestCheckDownloadDir(t *testing.T) {
+	// сервис работает и от root, которому права каталога не помеха, поэтому
+	// недоступный для записи каталог моделируется путём внутри обычного файла
+	base := t.TempDir()
+	file := filepath.Join(base, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		dir     string
+		wantErr bool
+	}{
+		{"existing dir", base, false},
+		{"missing dir created", filepath.Join(base, "a", "b"), false},
+		{"path under a file", filepath.Join(file, "downloads"), true},
+		{"path is a file", file, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, 1, Config{DownloadDir: tt.dir}, nil)
+			err := m.CheckDownloadDir()
+			if tt.wantErr {
+				if !errors.Is(err, ErrDownloadDirNotWritable) {
+					t.Fatalf("error = %v, want %v", err, ErrDownloadDirNotWritable)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckDownloadDir: %v", err)
+			}
+			entries, err := os.ReadDir(tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if strings.HasPrefix(e.Name(), ".write-probe-") {
+					t.Errorf("probe file %s left in %s", e.Name(), tt.dir)
+				}
+			}
+		})
+	}
+}
+
+func TestUnwritableDownloadDirFailsFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data"))
+	}))
+	defer srv.Close()
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newTestManager(t, 100, Config{AllowPrivateIPs: true}, nil)
+	// каталог стал недоступен уже после проверки при запуске
+	m.cfg.DownloadDir = filepath.Join(file, "downloads")
+	startWorkers(t, m, 1)
+	task, _, err := m.AddTask(TaskSpec{Files: []FileSpec{{URL: srv.URL + "/a.bin"}}})
+	if err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+	got := waitFinished(t, m, task.ID)
+	f := got.Files[0]
+	if f.Status != model.StatusError || !strings.HasPrefix(f.Error, "cannot create directory: ") {
+		t.Errorf("file status %q, error %q; want error starting with %q", f.Status, f.Error, "cannot create directory: ")
+	}
+}
+
+func TestDownloadErrorMarksDiskErrors(t *testing.T) {
+	m := newTestManager(t, 1, Config{}, nil)
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"disk error", &fs.PathError{Op: "write", Path: "/d/a.bin.part", Err: errors.New("no space left on device")},
+			"cannot write file: write /d/a.bin.part: no space left on device"},
+		{"network error", errors.New("connection reset by peer"), "connection reset by peer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if got := m.downloadError(ctx, ctx, tt.err); got != tt.want {
+				t.Errorf("downloadError = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
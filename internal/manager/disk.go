@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"hh03012025/internal/model"
+)
+
+// ErrDiskQuotaExceeded возвращается, если файл не помещается в
+// Config.MaxDiskUsage даже после вытеснения всех завершённых задач.
+var ErrDiskQuotaExceeded = errors.New("disk usage limit exceeded")
+
+// taskDiskUsage возвращает суммарный размер скачанных файлов задачи.
+func taskDiskUsage(t *model.Task) int64 {
+	var n int64
+	for _, f := range t.Files {
+		if f.Status == model.StatusCompleted {
+			n += f.Size
+		}
+	}
+	return n
+}
+
+// DiskUsage возвращает суммарный размер скачанных файлов задач в памяти.
+func (m *Manager) DiskUsage() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.diskUsage
+}
+
+// reserveDisk резервирует место под файл задания job размером size в
+// пределах Config.MaxDiskUsage. Если места не хватает, удаляются завершённые
+// задачи вместе с файлами, начиная с дольше всего не обновлявшихся; если
+// удалять больше нечего, возвращается ErrDiskQuotaExceeded. Файл
+// неизвестного размера проходит без резерва и учитывается после скачивания.
+// Повторный вызов для того же задания заменяет прежний резерв.
+func (m *Manager) reserveDisk(job Job, size int64) error {
+	if m.cfg.MaxDiskUsage <= 0 {
+		return nil
+	}
+	m.releaseDisk(job)
+	if size <= 0 {
+		return nil
+	}
+	if size > m.cfg.MaxDiskUsage {
+		return fmt.Errorf("%w: file is %d bytes, limit is %d", ErrDiskQuotaExceeded, size, m.cfg.MaxDiskUsage)
+	}
+	for {
+		m.mu.Lock()
+		excess := m.diskUsage + m.diskReserved + size - m.cfg.MaxDiskUsage
+		if excess <= 0 {
+			m.reserved[job] = size
+			m.diskReserved += size
+			m.mu.Unlock()
+			return nil
+		}
+		victim := m.oldestFinished()
+		m.mu.Unlock()
+		if victim == "" {
+			return fmt.Errorf("%w: need %d more bytes, nothing to evict", ErrDiskQuotaExceeded, excess)
+		}
+		// задача могла быть возобновлена после выбора: тогда removeTask
+		// откажет, и на следующем шаге выберется другая
+		if m.removeTask(victim, true) {
+			slog.Info("task evicted", "task_id", victim, "reason", "disk usage limit exceeded")
+		}
+	}
+}
+
+// releaseDisk снимает резерв задания job.
+func (m *Manager) releaseDisk(job Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n, ok := m.reserved[job]; ok {
+		m.diskReserved -= n
+		delete(m.reserved, job)
+	}
+}
+
+// oldestFinished возвращает ID завершённой задачи со скачанными файлами,
+// дольше всего не обновлявшейся, или "", если таких нет. Вызывать под m.mu.
+func (m *Manager) oldestFinished() string {
+	var victim *model.Task
+	for _, t := range m.tasks {
+		if !IsTerminal(t.Status) || taskDiskUsage(t) == 0 {
+			continue
+		}
+		if victim == nil || t.UpdatedAt.Before(victim.UpdatedAt) {
+			victim = t
+		}
+	}
+	if victim == nil {
+		return ""
+	}
+	return victim.ID
+}
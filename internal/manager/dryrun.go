@@ -0,0 +1,111 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"hh03012025/internal/download"
+)
+
+// Ограничения проверки доступности в ValidateTask: число одновременных
+// HEAD-запросов и время ожидания каждого.
+const (
+	dryRunParallel = 8
+	dryRunTimeout  = 10 * time.Second
+)
+
+// URLCheck — результат проверки одного URL без скачивания.
+type URLCheck struct {
+	URL   string `json:"url"`
+	Valid bool   `json:"valid"`
+	// Reachable заполняется только при проверке доступности: true, если
+	// сервер ответил на HEAD.
+	Reachable   *bool  `json:"reachable,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ValidateTask проверяет задачу так же, как AddTask, но не создаёт её и не
+// ставит файлы в очередь. Ошибка возвращается для параметров всей задачи,
+// а результаты по URL — в порядке spec.Files: URL считается корректным, если
+// это абсолютный http(s) URL, разрешённый политикой хостов вместе с его
+// зеркалами. При checkReachable к корректным URL параллельно отправляется
+// HEAD с заголовками файла, а из ответа берутся размер и тип содержимого;
+// файл больше лимита размера или свободного места считается некорректным.
+func (m *Manager) ValidateTask(ctx context.Context, spec TaskSpec, checkReachable bool) ([]URLCheck, error) {
+	if err := m.checkSpec(spec); err != nil {
+		return nil, err
+	}
+	checks := make([]URLCheck, len(spec.Files))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, dryRunParallel)
+	for i, s := range spec.Files {
+		checks[i].URL = m.redactor.URL(s.URL)
+		if err := m.checkURL(s.URL); err != nil {
+			checks[i].Error = err.Error()
+			continue
+		}
+		if err := m.checkHosts(s); err != nil {
+			checks[i].Error = err.Error()
+			continue
+		}
+		checks[i].Valid = true
+		if !checkReachable {
+			continue
+		}
+		wg.Add(1)
+		go func(c *URLCheck, s FileSpec) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			m.checkReachable(ctx, c, s)
+		}(&checks[i], s)
+	}
+	wg.Wait()
+	return checks, nil
+}
+
+// checkURL проверяет, что u — абсолютный URL со схемой, для которой
+// зарегистрирована реализация скачивания.
+func (m *Manager) checkURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("invalid URL %q: must be an absolute URL", u)
+	}
+	if !m.downloaders.Supports(parsed.Scheme) {
+		return fmt.Errorf("%w %q", download.ErrUnsupportedScheme, parsed.Scheme)
+	}
+	return nil
+}
+
+// checkReachable отправляет HEAD к URL файла и записывает результат в c.
+// Сервер, не поддерживающий HEAD, считается доступным.
+func (m *Manager) checkReachable(ctx context.Context, c *URLCheck, s FileSpec) {
+	ctx, cancel := context.WithTimeout(ctx, dryRunTimeout)
+	defer cancel()
+	opts := m.downloadOptions()
+	opts.Headers = s.Headers
+	info, err := m.downloaders.Preflight(ctx, s.URL, filepath.Join(m.cfg.DownloadDir, "dry-run"), opts)
+	// превышение лимитов означает, что сервер ответил, но скачивание
+	// завершится ошибкой
+	limited := errors.Is(err, download.ErrTooLarge) || errors.Is(err, download.ErrInsufficientDiskSpace)
+	reachable := err == nil || limited || errors.Is(err, download.ErrPreflightUnsupported)
+	c.Reachable = &reachable
+	c.Valid = !limited
+	if info.Size >= 0 {
+		c.Size = info.Size
+	}
+	c.ContentType = info.ContentType
+	if err != nil {
+		c.Error = err.Error()
+	}
+}
@@ -0,0 +1,54 @@
+package manager
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"hh03012025/internal/archive"
+)
+
+// Состояния распаковки архива (FileState.ExtractStatus).
+const (
+	ExtractExtracting = "extracting"
+	ExtractExtracted  = "extracted"
+	ExtractFailed     = "failed"
+)
+
+// extractArchive распаковывает скачанный файл dest в каталог задачи, если
+// это архив известного формата; остальные файлы пропускаются. Каталог
+// распаковки называется по имени архива без расширения. При
+// Config.DeleteExtractedArchives архив удаляется после успешной распаковки.
+func (m *Manager) extractArchive(ctx context.Context, job Job, dest string) error {
+	base, ok := archive.Base(filepath.Base(dest))
+	if !ok {
+		return nil
+	}
+	m.setExtractStatus(job, ExtractExtracting, base)
+	slog.Info("extraction started", "task_id", job.TaskID, "file_index", job.FileIndex, "dir", base)
+	if err := archive.Extract(ctx, dest, filepath.Join(filepath.Dir(dest), base)); err != nil {
+		m.setExtractStatus(job, ExtractFailed, "")
+		return err
+	}
+	if m.cfg.DeleteExtractedArchives {
+		if err := os.Remove(dest); err != nil {
+			slog.Error("archive delete error", "task_id", job.TaskID, "file_index", job.FileIndex, "error", err)
+		}
+	}
+	m.setExtractStatus(job, ExtractExtracted, base)
+	slog.Info("extraction completed", "task_id", job.TaskID, "file_index", job.FileIndex, "dir", base)
+	return nil
+}
+
+// setExtractStatus обновляет состояние распаковки файла и оповещает
+// подписчиков.
+func (m *Manager) setExtractStatus(job Job, status, dir string) {
+	m.mu.Lock()
+	if task, ok := m.tasks[job.TaskID]; ok && job.FileIndex < len(task.Files) {
+		file := &task.Files[job.FileIndex]
+		file.ExtractStatus, file.ExtractDir = status, dir
+		m.notify(job.TaskID)
+	}
+	m.mu.Unlock()
+}
@@ -0,0 +1,42 @@
+package manager
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// restoreDelays возвращает n случайных смещений от начала окна window,
+// упорядоченных по возрастанию: задание i ставится в очередь через
+// delays[i]. Порядок заданий сохраняется, а моменты постановки распределены
+// по окну случайно, поэтому после перезапуска запросы к источникам не
+// уходят все разом. При window <= 0 возвращает nil — без задержек.
+func restoreDelays(n int, window time.Duration) []time.Duration {
+	if window <= 0 || n == 0 {
+		return nil
+	}
+	delays := make([]time.Duration, n)
+	for i := range delays {
+		delays[i] = time.Duration(rand.Int64N(int64(window)))
+	}
+	slices.Sort(delays)
+	return delays
+}
+
+// sleepUntil ждёт наступления момента at. Возвращает false, если ctx
+// отменён раньше.
+func sleepUntil(ctx context.Context, at time.Time) bool {
+	d := time.Until(at)
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
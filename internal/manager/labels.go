@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Ограничения меток задачи: число меток и суммарная длина ключей и значений.
+const (
+	maxLabels      = 32
+	maxLabelsBytes = 4096
+)
+
+// ErrInvalidLabels возвращается, если метки задачи не проходят проверку.
+var ErrInvalidLabels = errors.New("invalid labels")
+
+// validateLabels проверяет метки задачи: ключ не пустой и не содержит ':'
+// и ',', которые разделяют селектор меток в списке задач, а число меток и
+// их суммарный размер ограничены.
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("%w: at most %d labels allowed", ErrInvalidLabels, maxLabels)
+	}
+	size := 0
+	for k, v := range labels {
+		if k == "" || strings.ContainsAny(k, ":,") {
+			return fmt.Errorf("%w: key %q must be non-empty and must not contain ':' or ','", ErrInvalidLabels, k)
+		}
+		size += len(k) + len(v)
+	}
+	if size > maxLabelsBytes {
+		return fmt.Errorf("%w: total size exceeds %d bytes", ErrInvalidLabels, maxLabelsBytes)
+	}
+	return nil
+}
+
+// matchLabels сообщает, есть ли у задачи все метки selector с теми же
+// значениями.
+func matchLabels(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if got, ok := labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
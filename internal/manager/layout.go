@@ -0,0 +1,53 @@
+package manager
+
+import (
+	"path/filepath"
+
+	"hh03012025/internal/model"
+)
+
+// Раскладки файлов в каталоге загрузок (Config.Layout, Task.Layout).
+const (
+	// LayoutPerTask — файлы задачи лежат в подкаталоге с её ID (или в
+	// DestSubdir) под своими именами. Раскладка по умолчанию.
+	LayoutPerTask = "per-task"
+	// LayoutFlatPrefixed — файлы всех задач лежат в одном каталоге (корне
+	// каталога загрузок или DestSubdir), а к имени добавляется префикс
+	// "{id}_", чтобы файлы разных задач не совпадали.
+	LayoutFlatPrefixed = "flat-prefixed"
+)
+
+// newTaskLayout возвращает раскладку для новой задачи. LayoutPerTask
+// сохраняется пустой строкой, как у задач, созданных до появления настройки.
+func (m *Manager) newTaskLayout() string {
+	if m.cfg.Layout == LayoutPerTask {
+		return ""
+	}
+	return m.cfg.Layout
+}
+
+// flat сообщает, что файлы задачи t сохраняются в общем каталоге с
+// префиксом ID.
+func flat(t *model.Task) bool {
+	return t.Layout == LayoutFlatPrefixed
+}
+
+// diskName возвращает имя, под которым файл f задачи t лежит на диске.
+func diskName(t *model.Task, f model.FileState) string {
+	if flat(t) {
+		return t.ID + "_" + f.Filename
+	}
+	return f.Filename
+}
+
+// ownsDir сообщает, что каталог taskDir(t) принадлежит только задаче t и
+// его можно удалить целиком.
+func ownsDir(t *model.Task) bool {
+	return t.DestSubdir == "" && !flat(t)
+}
+
+// fileRelPath возвращает путь файла f задачи t относительно каталога
+// загрузок.
+func fileRelPath(t *model.Task, f model.FileState) string {
+	return filepath.Join(taskDir(t), diskName(t, f))
+}
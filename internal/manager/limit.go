@@ -0,0 +1,71 @@
+package manager
+
+import "hh03012025/internal/model"
+
+// taskSlots — семафор параллельных скачиваний одной задачи. Задания сверх
+// лимита не занимают воркер в ожидании, а откладываются и возвращаются в
+// очередь, когда освобождается слот.
+type taskSlots struct {
+	running  int
+	deferred []Job
+}
+
+// taskLimit возвращает лимит параллельных скачиваний задачи: собственный,
+// если задан при создании, иначе Config.MaxConcurrentPerTask. 0 — без
+// ограничения.
+func (m *Manager) taskLimit(t *model.Task) int {
+	if t.MaxConcurrent > 0 {
+		return t.MaxConcurrent
+	}
+	return m.cfg.MaxConcurrentPerTask
+}
+
+// acquireSlot занимает слот задачи для задания job. Если лимит исчерпан,
+// задание откладывается до releaseSlot и возвращается false. Вызывается под
+// m.mu.
+func (m *Manager) acquireSlot(t *model.Task, job Job) bool {
+	limit := m.taskLimit(t)
+	if limit <= 0 {
+		return true
+	}
+	s := m.slots[t.ID]
+	if s == nil {
+		s = &taskSlots{}
+		m.slots[t.ID] = s
+	}
+	if s.running >= limit {
+		s.deferred = append(s.deferred, job)
+		return false
+	}
+	s.running++
+	return true
+}
+
+// releaseSlot освобождает слот задачи и возвращает в очередь первое
+// отложенное задание. Безопасно вызывать и для задачи без лимита.
+func (m *Manager) releaseSlot(taskID string) {
+	m.mu.Lock()
+	s := m.slots[taskID]
+	if s == nil {
+		m.mu.Unlock()
+		return
+	}
+	s.running--
+	var next *Job
+	if len(s.deferred) > 0 {
+		next = &s.deferred[0]
+		s.deferred = s.deferred[1:]
+	}
+	if s.running <= 0 && len(s.deferred) == 0 {
+		delete(m.slots, taskID)
+	}
+	priority := ""
+	if t, ok := m.tasks[taskID]; ok {
+		priority = t.Priority
+	}
+	m.mu.Unlock()
+	if next != nil {
+		// воркер не должен блокироваться на заполненной очереди
+		go m.queue.push(priority, *next)
+	}
+}
@@ -0,0 +1,49 @@
+package manager
+
+import (
+	"slices"
+	"sort"
+
+	"hh03012025/internal/model"
+)
+
+// ListOptions задаёт фильтр и страницу для ListTasks.
+type ListOptions struct {
+	// Statuses — допустимые общие статусы задач; пустой список — любые.
+	Statuses []model.Status
+	// Labels — метки, которые должны быть у задачи с теми же значениями.
+	Labels map[string]string
+	// Offset — число пропускаемых задач, Limit — максимальное число задач
+	// на странице (0 — без ограничения).
+	Offset int
+	Limit  int
+}
+
+// ListTasks возвращает копии задач, подходящих под фильтр opts, от новых к
+// старым, и общее число подходящих задач без учёта страницы.
+func (m *Manager) ListTasks(opts ListOptions) ([]*model.Task, int) {
+	m.mu.RLock()
+	var matched []*model.Task
+	for _, t := range m.tasks {
+		if (len(opts.Statuses) == 0 || slices.Contains(opts.Statuses, t.Status)) && matchLabels(t.Labels, opts.Labels) {
+			matched = append(matched, t)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+		return matched[i].ID < matched[j].ID
+	})
+	total := len(matched)
+	page := matched[min(max(opts.Offset, 0), total):]
+	if opts.Limit > 0 && len(page) > opts.Limit {
+		page = page[:opts.Limit]
+	}
+	out := make([]*model.Task, len(page))
+	for i, t := range page {
+		out[i] = copyTask(t)
+	}
+	m.mu.RUnlock()
+	return out, total
+}
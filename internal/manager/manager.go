@@ -2,17 +2,32 @@ package manager
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
-	"log"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
+
 	"hh03012025/internal/download"
+	"hh03012025/internal/metrics"
 	"hh03012025/internal/model"
+	"hh03012025/internal/redact"
+	"hh03012025/internal/store"
 	"hh03012025/internal/util"
+	"hh03012025/internal/webhook"
 )
 
 // Job — элемент очереди, определяющий конкретный файл в задаче для скачивания.
@@ -22,73 +37,840 @@ type Job struct {
 	FileIndex int
 }
 
+// DefaultDownloadTimeout — ограничение времени скачивания одного файла,
+// используемое, если в Config не задано иное.
+const DefaultDownloadTimeout = 30 * time.Minute
+
+// DefaultIdleTimeout — рекомендуемый таймаут простоя: если за это время не
+// пришло ни одного байта, скачивание прерывается.
+const DefaultIdleTimeout = time.Minute
+
+// DefaultIdempotencyTTL — время, в течение которого ключ идемпотентности
+// связан с созданной задачей.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// Config содержит настраиваемые параметры менеджера. Нулевые значения полей
+// заменяются значениями по умолчанию.
+type Config struct {
+	// DownloadDir — корневой каталог для скачанных файлов. Файлы задачи
+	// сохраняются в подкаталог с её ID или в TaskSpec.DestSubdir.
+	DownloadDir string
+	// Layout — раскладка файлов новых задач: LayoutPerTask (по умолчанию)
+	// или LayoutFlatPrefixed. Задача запоминает раскладку при создании,
+	// поэтому смена настройки не теряет файлы уже созданных задач.
+	Layout string
+	// FilenameTemplate — шаблон имён файлов новых задач (см.
+	// download.NameTemplate); задача может задать свой в
+	// TaskSpec.FilenameTemplate. nil — имя выводится из URL.
+	FilenameTemplate *download.NameTemplate
+	// DownloadTimeout ограничивает время скачивания одного файла. По истечении
+	// скачивание прерывается, а файл помечается как "error".
+	DownloadTimeout time.Duration
+	// IdleTimeout прерывает скачивание, если данные не поступают дольше
+	// указанного времени. 0 — проверка отключена.
+	IdleTimeout time.Duration
+	// DownloadRetries — сколько раз повторить скачивание файла после
+	// временной ошибки (ответ с кодом из RetryableStatuses, сбой сети).
+	// Постоянные ошибки попыток не расходуют. Все попытки укладываются в
+	// DownloadTimeout. 0 — без повторов.
+	DownloadRetries int
+	// RetryBackoff — пауза перед первой повторной попыткой, каждая
+	// следующая вдвое длиннее. Retry-After ответов 429 и 503 имеет
+	// приоритет. 0 — DefaultRetryBackoff.
+	RetryBackoff time.Duration
+	// RetryableStatuses — коды ответа, после которых попытка повторяется.
+	// nil — DefaultRetryableStatuses.
+	RetryableStatuses []int
+	// MaxFileSize ограничивает размер одного файла в байтах. Файлы большего
+	// размера помечаются как "error". 0 — без ограничения.
+	MaxFileSize int64
+	// S3Endpoint — адрес S3-совместимого хранилища для URL s3://. Пустое
+	// значение — AWS S3.
+	S3Endpoint string
+	// S3PathStyle включает адресацию endpoint/bucket/key, нужную
+	// большинству S3-совместимых хранилищ.
+	S3PathStyle bool
+	// Downloaders сопоставляет схемам URL реализации скачивания. nil —
+	// download.DefaultRegistry (http, https, ftp, ftps, ftpes и s3).
+	Downloaders *download.Registry
+	// DownloadChunks > 1 включает скачивание больших файлов параллельно
+	// несколькими частями, если источник поддерживает Range.
+	DownloadChunks int
+	// ChunkMinSize — минимальный размер файла для скачивания частями.
+	// 0 — download.DefaultChunkMinSize.
+	ChunkMinSize int64
+	// ResumeDownloads включает продолжение прерванных HTTP-скачиваний с
+	// места остановки по временному файлу .part (с проверкой If-Range).
+	ResumeDownloads bool
+	// IdempotencyTTL — срок жизни ключа идемпотентности. После его истечения
+	// запрос с тем же ключом создаёт новую задачу.
+	IdempotencyTTL time.Duration
+	// KeepDuplicateURLs отключает удаление повторяющихся URL внутри задачи.
+	// По умолчанию каждый уникальный URL скачивается один раз.
+	KeepDuplicateURLs bool
+	// CheckDiskSpace включает проверку свободного места перед скачиванием
+	// файла: должно хватать на Content-Length плюс MinFreeDisk.
+	CheckDiskSpace bool
+	// MinFreeDisk — запас свободного места в байтах, который должен остаться
+	// после скачивания. Для файлов неизвестного размера проверяется только он.
+	MinFreeDisk int64
+	// MaxRedirects ограничивает число редиректов при скачивании.
+	// 0 — download.DefaultMaxRedirects, отрицательное — редиректы запрещены.
+	MaxRedirects int
+	// SameHostRedirects разрешает только редиректы на исходный хост.
+	SameHostRedirects bool
+	// HostPolicy ограничивает хосты, с которых разрешено скачивание. Она
+	// проверяется при создании задачи и повторно после каждого редиректа.
+	HostPolicy download.HostPolicy
+	// RejectBlockedHosts определяет реакцию на запрещённые URL при создании
+	// задачи: true — задача целиком отклоняется, false — такие файлы сразу
+	// получают статус "error", а остальные скачиваются.
+	RejectBlockedHosts bool
+	// AllowPrivateIPs отключает защиту от SSRF: по умолчанию соединения с
+	// приватными, loopback и link-local адресами запрещены.
+	AllowPrivateIPs bool
+	// HeadPreflight включает HEAD-запрос перед скачиванием: размер и тип
+	// содержимого становятся известны сразу, а лимиты размера и свободного
+	// места проверяются до передачи тела. Удваивает число запросов.
+	HeadPreflight bool
+	// KeepContentEncoding сохраняет ответ со сжатием Content-Encoding (gzip,
+	// deflate) как есть. По умолчанию такой ответ распаковывается.
+	KeepContentEncoding bool
+	// RequireContentType отклоняет ответ без заголовка Content-Type, если для
+	// файла задан ожидаемый тип (FileSpec.ExpectedContentType). По умолчанию
+	// такой ответ принимается.
+	RequireContentType bool
+	// RejectHTMLPages включает проверку начала тела ответа: HTML-страница
+	// вместо файла другого типа (например, страница «файл не найден» со
+	// статусом 200) завершает скачивание ошибкой.
+	RejectHTMLPages bool
+	// MaxURLsPerTask ограничивает число URL в одной задаче. 0 — без
+	// ограничения.
+	MaxURLsPerTask int
+	// MaxTasks ограничивает число задач в памяти: при превышении удаляются
+	// завершённые задачи, дольше всего не обновлявшиеся. Незавершённые задачи
+	// не удаляются. 0 — без ограничения.
+	MaxTasks int
+	// DeleteEvictedFiles удаляет с диска файлы вытесненных задач.
+	DeleteEvictedFiles bool
+	// TaskTTL — через сколько после последнего обновления завершённая задача
+	// удаляется вместе с файлами (см. ReapLoop). 0 — задачи не удаляются.
+	TaskTTL time.Duration
+	// DeleteExtractedArchives удаляет архив после успешной распаковки (см.
+	// TaskSpec.Extract).
+	DeleteExtractedArchives bool
+	// MaxConcurrentPerTask ограничивает число одновременно скачиваемых
+	// файлов одной задачи, чтобы большая задача не занимала всех воркеров.
+	// Может быть переопределён для задачи (TaskSpec.MaxConcurrent). 0 — без
+	// ограничения.
+	MaxConcurrentPerTask int
+	// UserAgent — User-Agent запросов к источникам. Пустое значение —
+	// download.DefaultUserAgent.
+	UserAgent string
+	// DefaultHeaders — заголовки, отправляемые с каждым запросом к
+	// источникам. Заголовки файла (FileSpec.Headers) имеют приоритет.
+	DefaultHeaders http.Header
+	// Proxy — URL прокси-сервера (http, https или socks5) для запросов к
+	// источникам. Пустое значение — прокси из переменных окружения
+	// HTTP_PROXY, HTTPS_PROXY и NO_PROXY.
+	Proxy string
+	// NoProxy — хосты, к которым при заданном Proxy нужно подключаться
+	// напрямую (см. download.TransportConfig.NoProxy).
+	NoProxy []string
+	// Параметры пула соединений с источниками (см. http.Transport). Нулевые
+	// значения — настройки http.DefaultTransport.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	// MaxConnections ограничивает число одновременных запросов к
+	// источникам во всех скачиваниях, включая части параллельного
+	// скачивания и HEAD-запросы (см. download.ConnLimiter). 0 — без
+	// ограничения.
+	MaxConnections int
+	// ConnectTimeout ограничивает установку соединения с источником:
+	// недоступный хост быстро даёт ошибку, а сама передача может длиться
+	// дольше. 0 — download.DefaultConnectTimeout.
+	ConnectTimeout time.Duration
+	// RootCAs — корневые сертификаты для проверки источников, например с
+	// добавленным внутренним CA. nil — системные сертификаты.
+	RootCAs *x509.CertPool
+	// TLSInsecureSkipVerify отключает проверку TLS-сертификатов источников.
+	// Опасно: скачиваемые файлы могут быть подменены при перехвате
+	// соединения; предназначено только для внутренних серверов с
+	// самоподписанными сертификатами, для которых нельзя задать RootCAs.
+	TLSInsecureSkipVerify bool
+	// DedupTasks включает поиск дубликатов: если незавершённая задача с тем
+	// же набором URL (без учёта порядка) уже есть, AddTask возвращает её
+	// вместе с ErrDuplicateTask вместо создания новой.
+	DedupTasks bool
+	// RunPastStartAt запускает сразу задачи, время отложенного запуска
+	// которых уже прошло, вместо отказа с ErrStartAtInPast.
+	RunPastStartAt bool
+	// RestoreJitter — окно, по которому случайно распределяется постановка
+	// в очередь файлов, восстановленных из снапшота, чтобы после перезапуска
+	// воркеры не обращались ко всем источникам одновременно. 0 — все файлы
+	// ставятся в очередь сразу.
+	RestoreJitter time.Duration
+	// SensitiveQueryKeys — параметры запроса, значения которых скрываются в
+	// URL перед логированием и сохранением. nil — redact.DefaultQueryKeys.
+	SensitiveQueryKeys []string
+	// IDPrefix добавляется в начало ID новых задач (например, "task_"),
+	// чтобы их было проще отличить в логах. Допустимые символы — см.
+	// util.ValidIDPrefix. Пустое значение — ID без префикса.
+	IDPrefix string
+	// IDFormat — формат ID новых задач: util.IDFormatHex (по умолчанию) или
+	// util.IDFormatUUID.
+	IDFormat string
+	// MaxActiveTasks ограничивает число незавершённых задач: новые задачи
+	// сверх лимита отклоняются с ErrTooManyTasks. 0 — без ограничения.
+	MaxActiveTasks int
+	// MaxDiskUsage ограничивает суммарный размер скачанных файлов задач в
+	// памяти: файлу, который не помещается в лимит, освобождается место
+	// удалением завершённых задач вместе с файлами, начиная с дольше всего
+	// не обновлявшихся. Если удалять нечего, файл получает статус "error"
+	// (ErrDiskQuotaExceeded). 0 — без ограничения.
+	MaxDiskUsage int64
+}
+
+// FileSpec описывает файл, запрошенный при создании задачи: URL и
+// необязательные заголовки запроса.
+type FileSpec struct {
+	URL string
+	// Headers — дополнительные заголовки запроса. Они могут содержать
+	// секреты, поэтому хранятся только в памяти: если сервис перезапустится
+	// до окончания скачивания, файл получит статус "error" с
+	// ErrSecretsLost, а не будет скачиваться без заголовков. Такую задачу
+	// нужно создать заново.
+	Headers map[string]string
+	// Mirrors — запасные URL того же содержимого; пробуются по порядку, если
+	// скачивание с URL не удалось.
+	Mirrors []string
+	// Accept — заголовок Accept запроса для выбора представления ресурса
+	// (JSON, CSV и т. п.). Пустое значение — заголовок не отправляется.
+	Accept string
+	// ExpectedContentType — ожидаемый Content-Type ответа или его префикс
+	// ("image/"). Ответ другого типа завершает скачивание ошибкой.
+	ExpectedContentType string
+	// SHA256 — ожидаемая контрольная сумма содержимого в hex. Скачанный файл
+	// с другой суммой удаляется, а файл получает статус "error".
+	SHA256 string
+	// Filename — имя файла в каталоге задачи вместо выведенного из URL или
+	// шаблона.
+	Filename string
+}
+
+// ErrTooManyTasks возвращается AddTask, если число незавершённых задач
+// достигло Config.MaxActiveTasks. Клиенту стоит повторить запрос позже.
+var ErrTooManyTasks = errors.New("too many active tasks")
+
+// ErrIdempotencyConflict возвращается, если ключ идемпотентности уже
+// использован для задачи с другим списком URL.
+var ErrIdempotencyConflict = errors.New("idempotency key reused with different URLs")
+
+// TaskSpec описывает запрос на создание задачи.
+type TaskSpec struct {
+	Files []FileSpec
+	// IdempotencyKey, если задан, защищает от повторного создания задачи
+	// при повторной отправке того же запроса.
+	IdempotencyKey string
+	// CallbackURL, если задан, получает POST с итогами задачи, когда она
+	// переходит в терминальное состояние.
+	CallbackURL string
+	// FileCallbackURL, если задан, получает POST об итоге каждого файла,
+	// когда тот переходит в терминальное состояние, независимо от
+	// CallbackURL.
+	FileCallbackURL string
+	// FilenameTemplate — шаблон имён файлов задачи вместо
+	// Config.FilenameTemplate, в синтаксисе download.NameTemplate.
+	FilenameTemplate string
+	// Priority — приоритет задачи: high, normal или low (пустой — normal).
+	Priority string
+	// Extract включает распаковку архивов zip и tar.gz после скачивания в
+	// каталог задачи.
+	Extract bool
+	// MaxConcurrent переопределяет Config.MaxConcurrentPerTask для задачи.
+	// 0 — использовать общий лимит.
+	MaxConcurrent int
+	// DestSubdir — относительный путь внутри каталога загрузок, куда
+	// сохраняются файлы задачи вместо каталога с её ID.
+	DestSubdir string
+	// Labels — произвольные метки задачи для фильтрации списка задач.
+	Labels map[string]string
+	// StartAt, если задан, откладывает скачивание файлов задачи до этого
+	// времени.
+	StartAt time.Time
+	// TraceParent — контекст трассировки вызывающего (например, из заголовка
+	// traceparent): спан задачи становится его дочерним.
+	TraceParent trace.SpanContext
+	// Deadline, если задан, — срок выполнения задачи: по его истечении
+	// активные скачивания прерываются, а незавершённые файлы получают статус
+	// "error".
+	Deadline time.Time
+	// MaxDuration задаёт срок как длительность от начала выполнения (от
+	// создания задачи или от StartAt). Исключает Deadline.
+	MaxDuration time.Duration
+}
+
+// ErrTaskCanceled — причина отмены контекста задачи по запросу пользователя.
+var ErrTaskCanceled = errors.New("task canceled")
+
+// taskControl хранит контекст задачи и функцию его отмены. Контекст задачи
+// не зависит от корневого: отмена одной задачи не затрагивает остальные.
+type taskControl struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
 // Manager управляет задачами: принимает новые, планирует скачивание файлов,
 // запускает рабочие воркеры, следит за состоянием и сохраняет/восстанавливает
-// состояние из снапшотов. Допускает параллельный доступ.
+// состояние через хранилище (store.Store). Допускает параллельный доступ.
 type Manager struct {
 	tasks    map[string]*model.Task
+	controls map[string]taskControl
+	idemKeys map[string]string // ключ идемпотентности -> ID задачи
+	urlIndex map[string]string // хеш набора URL -> ID последней задачи с ним
+	speeds   map[Job]*speedSample
+	subs     map[string]map[chan struct{}]struct{}
 	mu       sync.RWMutex
-	jobs     chan Job
-	wg       sync.WaitGroup
-	draining bool
+	queue    *jobQueue
+	// slots — занятые слоты и отложенные задания задач с лимитом
+	// параллельных скачиваний.
+	slots map[string]*taskSlots
+	// restored — файлы из снапшота, которые StartWorkers поставит в очередь
+	// после запуска воркеров.
+	restored []queuedJob
+	// workers — целевое число воркеров, running — число запущенных
+	// горутин воркеров; workerCtx — контекст, с которым они запущены. Пока
+	// running больше workers, воркеры завершаются перед следующим заданием;
+	// закрытие shrink будит свободных воркеров, чтобы они это проверили.
+	workers   int
+	running   int
+	workerCtx context.Context
+	shrink    chan struct{}
+	// unfinished — число задач в нетерминальном статусе, для проверки
+	// Config.MaxActiveTasks без обхода всех задач.
+	unfinished int
+	// retryStatuses — множество Config.RetryableStatuses.
+	retryStatuses map[int]bool
+	// diskUsage — суммарный размер скачанных (completed) файлов задач в
+	// памяти; diskReserved — место, зарезервированное под скачиваемые
+	// файлы известного размера (reserved) при заданном Config.MaxDiskUsage.
+	diskUsage    int64
+	diskReserved int64
+	reserved     map[Job]int64
+	// spans — открытые спаны трассировки незавершённых задач.
+	spans map[string]trace.Span
+	// active — задания, которые воркеры обрабатывают прямо сейчас, и время
+	// начала их обработки.
+	active map[Job]time.Time
+	// scheduleWake будит ScheduleLoop при появлении отложенной задачи.
+	scheduleWake chan struct{}
+	wg           sync.WaitGroup
+	draining     bool
+	cfg          Config
+	store        store.Store
+	webhooks     *webhook.Sender
+	// client — общий для всех скачиваний HTTP-клиент, чтобы соединения с
+	// источниками переиспользовались.
+	client *http.Client
+	// redactor скрывает секреты в URL файлов, ошибках и логах.
+	redactor *redact.Redactor
+	// conns — общий для всех скачиваний лимит соединений с источниками.
+	conns *download.ConnLimiter
+	// s3 — клиент для URL s3://, создаётся при первом использовании.
+	s3 *download.S3Client
+	// downloaders выбирает реализацию скачивания по схеме URL.
+	downloaders *download.Registry
+	// snapshotMu не даёт периодическому и ручному снапшотам писать в
+	// хранилище одновременно.
+	snapshotMu sync.Mutex
+	// persistMu упорядочивает поштучные записи в TaskStore, чтобы более
+	// старая копия задачи не перезаписала более новую.
+	persistMu sync.Mutex
 }
 
 // NewManager создаёт и возвращает менеджер. Параметр queueSize задаёт
-// ёмкость буферизированной очереди заданий (jobs).
-func NewManager(queueSize int) *Manager {
-	return &Manager{
-		tasks: make(map[string]*model.Task),
-		jobs:  make(chan Job, queueSize),
+// ёмкость очереди заданий каждого приоритета, cfg — параметры скачивания,
+// st — хранилище состояния (nil — состояние не сохраняется).
+func NewManager(queueSize int, cfg Config, st store.Store) *Manager {
+	if cfg.DownloadDir == "" {
+		cfg.DownloadDir = "downloads"
+	}
+	if cfg.Layout == "" {
+		cfg.Layout = LayoutPerTask
+	}
+	if cfg.DownloadTimeout <= 0 {
+		cfg.DownloadTimeout = DefaultDownloadTimeout
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+	if cfg.RetryableStatuses == nil {
+		cfg.RetryableStatuses = DefaultRetryableStatuses()
+	}
+	if cfg.IdempotencyTTL <= 0 {
+		cfg.IdempotencyTTL = DefaultIdempotencyTTL
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = download.DefaultUserAgent
+	}
+	if cfg.SensitiveQueryKeys == nil {
+		cfg.SensitiveQueryKeys = redact.DefaultQueryKeys
 	}
+	m := &Manager{
+		tasks:        make(map[string]*model.Task),
+		controls:     make(map[string]taskControl),
+		idemKeys:     make(map[string]string),
+		urlIndex:     make(map[string]string),
+		speeds:       make(map[Job]*speedSample),
+		subs:         make(map[string]map[chan struct{}]struct{}),
+		shrink:       make(chan struct{}),
+		active:       make(map[Job]time.Time),
+		reserved:     make(map[Job]int64),
+		spans:        make(map[string]trace.Span),
+		scheduleWake: make(chan struct{}, 1),
+		slots:        make(map[string]*taskSlots),
+		queue:        newJobQueue(queueSize),
+		cfg:          cfg,
+		store:        st,
+		webhooks:     webhook.NewSender(),
+		redactor:     redact.New(cfg.SensitiveQueryKeys),
+		s3:           download.NewS3Client(download.S3Config{Endpoint: cfg.S3Endpoint, PathStyle: cfg.S3PathStyle}),
+		downloaders:  cfg.Downloaders,
+		conns:        download.NewConnLimiter(cfg.MaxConnections),
+	}
+	if m.downloaders == nil {
+		m.downloaders = download.DefaultRegistry()
+	}
+	m.retryStatuses = make(map[int]bool, len(cfg.RetryableStatuses))
+	for _, code := range cfg.RetryableStatuses {
+		m.retryStatuses[code] = true
+	}
+	transport := download.NewTransport(download.TransportConfig{
+		Proxy:                 cfg.Proxy,
+		NoProxy:               cfg.NoProxy,
+		BlockPrivateIPs:       !cfg.AllowPrivateIPs,
+		DecodeContentEncoding: !cfg.KeepContentEncoding,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		ConnectTimeout:        cfg.ConnectTimeout,
+		RootCAs:               cfg.RootCAs,
+		InsecureSkipVerify:    cfg.TLSInsecureSkipVerify,
+	})
+	if cfg.TLSInsecureSkipVerify {
+		slog.Warn("TLS certificate verification is DISABLED for downloads; files can be tampered with in transit")
+	}
+	m.client = download.NewClient(transport, m.downloadOptions())
+	return m
 }
 
-// AddTask создаёт новую задачу по списку URL, присваивает ей уникальный
+// AddTask создаёт новую задачу по списку файлов, присваивает ей уникальный
 // идентификатор и ставит все файлы в очередь на скачивание. Если менеджер
 // находится в режиме draining (при остановке), задания будут поставлены
 // только после перезапуска. В поле Status возвращаемой задачи можно понять,
 // были ли начаты скачивания.
-func (m *Manager) AddTask(urls []string) (*model.Task, error) {
-	if len(urls) == 0 {
-		return nil, errors.New("task must contain at least one URL")
+//
+// Если задан spec.IdempotencyKey и с этим ключом уже создана задача с тем же
+// списком URL, возвращается её копия и created == false. Если список URL
+// отличается, возвращается ErrIdempotencyConflict.
+func (m *Manager) AddTask(spec TaskSpec) (task *model.Task, created bool, err error) {
+	if err := m.checkSpec(spec); err != nil {
+		return nil, false, err
+	}
+	specs := spec.Files
+	// значения уже проверены checkSpec
+	priority, _ := normalizePriority(spec.Priority)
+	subdir, _ := cleanSubdir(spec.DestSubdir)
+	if !m.cfg.KeepDuplicateURLs {
+		specs = dedupSpecs(specs)
 	}
-	id := util.GenerateID()
+	id := util.NewID(m.cfg.IDFormat, m.cfg.IDPrefix)
 	now := time.Now().UTC()
-	files := make([]model.FileState, len(urls))
-	for i, u := range urls {
-		files[i] = model.FileState{URL: u, Status: "pending"}
+	var startAt *time.Time
+	if spec.StartAt.After(now) {
+		at := spec.StartAt.UTC()
+		startAt = &at
+	}
+	files := make([]model.FileState, len(specs))
+	for i, s := range specs {
+		files[i] = model.FileState{URL: s.URL, Status: model.StatusPending, Headers: s.Headers, Mirrors: s.Mirrors,
+			Accept: s.Accept, ExpectedContentType: s.ExpectedContentType, SHA256: strings.ToLower(s.SHA256), Filename: s.Filename}
+		m.redactSecrets(&files[i])
+		files[i].Secrets = hasSecrets(files[i])
+		if err := m.checkHosts(s); err != nil {
+			if m.cfg.RejectBlockedHosts {
+				return nil, false, err
+			}
+			files[i].Status = model.StatusError
+			files[i].Error = err.Error()
+		}
+	}
+	tmpl := m.cfg.FilenameTemplate
+	if spec.FilenameTemplate != "" {
+		// шаблон уже проверен checkSpec
+		tmpl, _ = download.ParseNameTemplate(spec.FilenameTemplate)
+	}
+	if err := assignFileNames(files, tmpl, id, now); err != nil {
+		return nil, false, err
 	}
 	t := &model.Task{
-		ID:        id,
-		Files:     files,
-		Status:    "pending",
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:              id,
+		Files:           files,
+		Status:          model.StatusPending,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		IdempotencyKey:  spec.IdempotencyKey,
+		CallbackURL:     spec.CallbackURL,
+		FileCallbackURL: spec.FileCallbackURL,
+		Priority:        priority,
+		Extract:         spec.Extract,
+		MaxConcurrent:   spec.MaxConcurrent,
+		DestSubdir:      subdir,
+		Layout:          m.newTaskLayout(),
+		Labels:          maps.Clone(spec.Labels),
+		StartAt:         startAt,
+		Deadline:        taskDeadline(spec, now, startAt),
+		URLSetHash:      urlSetHash(files),
+	}
+	if startAt != nil {
+		t.Status = model.StatusScheduled
 	}
 	m.mu.Lock()
+	if existing := m.lookupIdempotencyKey(spec.IdempotencyKey, now); existing != nil {
+		defer m.mu.Unlock()
+		if !sameURLs(existing.Files, files) {
+			return nil, false, ErrIdempotencyConflict
+		}
+		return copyTask(existing), false, nil
+	}
+	if m.cfg.DedupTasks {
+		if existing := m.lookupDuplicate(t.URLSetHash); existing != nil {
+			// повтор с тем же ключом идемпотентности вернёт ту же задачу
+			if spec.IdempotencyKey != "" {
+				m.idemKeys[spec.IdempotencyKey] = existing.ID
+			}
+			c := copyTask(existing)
+			m.mu.Unlock()
+			slog.Info("duplicate task", "task_id", c.ID)
+			return c, false, ErrDuplicateTask
+		}
+	}
+	if m.cfg.MaxActiveTasks > 0 && m.unfinished >= m.cfg.MaxActiveTasks {
+		m.mu.Unlock()
+		return nil, false, fmt.Errorf("%w: limit is %d", ErrTooManyTasks, m.cfg.MaxActiveTasks)
+	}
+	if spec.IdempotencyKey != "" {
+		m.idemKeys[spec.IdempotencyKey] = id
+	}
+	m.urlIndex[t.URLSetHash] = id
 	m.tasks[id] = t
+	m.unfinished++
+	m.newTaskControl(id)
+	m.startTaskSpan(t, spec.TraceParent)
+	m.armDeadline(t)
+	var queue []int
+	for idx, f := range files {
+		if f.Status == model.StatusPending {
+			queue = append(queue, idx)
+		} else {
+			m.notifyFile(t, idx)
+		}
+	}
+	var finished *model.Task
+	if len(queue) == 0 {
+		// Все файлы отклонены политикой хостов — задача сразу завершена.
+		m.recomputeStatus(t)
+		m.endTaskSpan(t)
+		if t.CallbackURL != "" {
+			finished = copyTask(t)
+		}
+	}
+	// файлы отложенной задачи поставит в очередь ScheduleLoop
+	enqueue := !m.draining && startAt == nil
+	// после снятия блокировки задачу меняют воркеры, поэтому вызывающему
+	// возвращается копия
+	c := copyTask(t)
 	m.mu.Unlock()
-	if !m.draining {
-		for idx := range files {
-			m.enqueueJob(t.ID, idx)
+	metrics.TasksCreated.Inc()
+	metrics.FilesFailed.Add(float64(len(files) - len(queue)))
+	slog.Info("task created", "task_id", id, "files", len(files))
+	if enqueue {
+		for _, idx := range queue {
+			m.enqueueJob(id, idx)
+		}
+	}
+	m.persistTask(id)
+	if startAt != nil {
+		m.wakeScheduler()
+	}
+	if finished != nil {
+		go m.notifyCompletion(finished)
+	}
+	m.evictTasks()
+	return c, true, nil
+}
+
+// checkSpec проверяет параметры задачи, не зависящие от отдельных URL:
+// число файлов, callback_url, лимит параллельности, приоритет, каталог
+// назначения, метки, время отложенного запуска и срок выполнения.
+func (m *Manager) checkSpec(spec TaskSpec) error {
+	if len(spec.Files) == 0 {
+		return errors.New("task must contain at least one URL")
+	}
+	if m.cfg.MaxURLsPerTask > 0 && len(spec.Files) > m.cfg.MaxURLsPerTask {
+		return fmt.Errorf("task must contain at most %d URLs, got %d", m.cfg.MaxURLsPerTask, len(spec.Files))
+	}
+	if err := checkCallbackURL("callback_url", spec.CallbackURL); err != nil {
+		return err
+	}
+	if err := checkCallbackURL("file_callback_url", spec.FileCallbackURL); err != nil {
+		return err
+	}
+	if _, err := download.ParseNameTemplate(spec.FilenameTemplate); err != nil {
+		return fmt.Errorf("invalid filename_template: %w", err)
+	}
+	if spec.MaxConcurrent < 0 {
+		return errors.New("max_concurrent must not be negative")
+	}
+	if _, err := normalizePriority(spec.Priority); err != nil {
+		return err
+	}
+	if _, err := cleanSubdir(spec.DestSubdir); err != nil {
+		return err
+	}
+	if err := validateLabels(spec.Labels); err != nil {
+		return err
+	}
+	if err := checkFileSpecs(spec.Files); err != nil {
+		return err
+	}
+	if !spec.StartAt.IsZero() && !spec.StartAt.After(time.Now()) && !m.cfg.RunPastStartAt {
+		return ErrStartAtInPast
+	}
+	if spec.MaxDuration < 0 {
+		return errors.New("max_duration must not be negative")
+	}
+	if spec.MaxDuration > 0 && !spec.Deadline.IsZero() {
+		return errors.New("deadline and max_duration are mutually exclusive")
+	}
+	if !spec.Deadline.IsZero() && !spec.Deadline.After(time.Now()) {
+		return ErrDeadlineInPast
+	}
+	return nil
+}
+
+// redactSecrets скрывает учётные данные и чувствительные параметры запроса в
+// URL и зеркалах файла, сохраняя исходные URL в AuthURLs: в состояние файла,
+// снапшот, ответы API и логи попадают только URL без секретов.
+func (m *Manager) redactSecrets(f *model.FileState) {
+	redact := func(u string) string {
+		r := m.redactor.URL(u)
+		if r != u {
+			if f.AuthURLs == nil {
+				f.AuthURLs = make(map[string]string)
+			}
+			f.AuthURLs[r] = u
+		}
+		return r
+	}
+	f.URL = redact(f.URL)
+	if len(f.Mirrors) > 0 {
+		mirrors := make([]string, len(f.Mirrors))
+		for i, u := range f.Mirrors {
+			mirrors[i] = redact(u)
+		}
+		f.Mirrors = mirrors
+	}
+}
+
+// ErrSecretsLost — ошибка файла, секреты запроса которого (заголовки или
+// учётные данные и подписи в URL) не пережили перезапуск сервиса: без них
+// скачивание не пройдёт авторизацию.
+var ErrSecretsLost = errors.New("credentials not persisted across restart, resubmit the task")
+
+// hasSecrets сообщает, что запрос файла f использует секреты, которые
+// хранятся только в памяти: дополнительные заголовки или исходные URL со
+// скрытыми в состоянии секретами.
+func hasSecrets(f model.FileState) bool {
+	return len(f.Headers) > 0 || len(f.AuthURLs) > 0
+}
+
+// secretsLost сообщает, что секреты запроса файла f были, но утеряны при
+// перезапуске, и скачать файл повторно нельзя.
+func secretsLost(f model.FileState) bool {
+	return f.Secrets && !hasSecrets(f)
+}
+
+// checkHosts проверяет по политике хостов основной URL файла и его зеркала.
+func (m *Manager) checkHosts(s FileSpec) error {
+	for _, u := range append([]string{s.URL}, s.Mirrors...) {
+		if err := m.cfg.HostPolicy.CheckURL(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignFileNames выбирает имена для файлов задачи taskID, созданной в
+// created, у которых оно ещё не задано: по шаблону tmpl или, если он nil, из
+// URL. Полученные имена при совпадении дополняются суффиксом "(n)", поэтому
+// файлы одной задачи не перезаписывают друг друга. Уже выбранные имена
+// (например, из снапшота) сохраняются. Ошибка возвращается, если шаблон дал
+// недопустимое имя.
+func assignFileNames(files []model.FileState, tmpl *download.NameTemplate, taskID string, created time.Time) error {
+	used := make(map[string]bool, len(files))
+	for _, f := range files {
+		if f.Filename != "" {
+			used[strings.ToLower(f.Filename)] = true
+		}
+	}
+	for i := range files {
+		if files[i].Filename == "" {
+			name := download.DeriveFileName(files[i].URL, i)
+			if tmpl != nil {
+				var err error
+				if name, err = tmpl.Execute(download.NewNameData(taskID, i, files[i].URL, created)); err != nil {
+					return err
+				}
+			}
+			files[i].Filename = download.UniqueFileName(name, used)
+		}
+	}
+	return nil
+}
+
+// dedupSpecs удаляет повторяющиеся URL, сохраняя порядок и первое вхождение
+// (включая его заголовки).
+func dedupSpecs(specs []FileSpec) []FileSpec {
+	seen := make(map[string]struct{}, len(specs))
+	out := make([]FileSpec, 0, len(specs))
+	for _, s := range specs {
+		if _, ok := seen[s.URL]; ok {
+			continue
+		}
+		seen[s.URL] = struct{}{}
+		out = append(out, s)
+	}
+	return out
+}
+
+// lookupIdempotencyKey возвращает задачу, созданную с ключом key, если срок
+// действия ключа не истёк. Просроченные ключи удаляются. Вызывать под m.mu.
+func (m *Manager) lookupIdempotencyKey(key string, now time.Time) *model.Task {
+	if key == "" {
+		return nil
+	}
+	id, ok := m.idemKeys[key]
+	if !ok {
+		return nil
+	}
+	t, ok := m.tasks[id]
+	if !ok || now.Sub(t.CreatedAt) > m.cfg.IdempotencyTTL {
+		delete(m.idemKeys, key)
+		return nil
+	}
+	return t
+}
+
+// sameURLs сообщает, совпадают ли списки URL двух наборов файлов с учётом порядка.
+func sameURLs(a, b []model.FileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].URL != b[i].URL {
+			return false
+		}
+	}
+	return true
+}
+
+// newTaskControl создаёт контекст для задачи id. Вызывать под m.mu.
+func (m *Manager) newTaskControl(id string) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	m.controls[id] = taskControl{ctx: ctx, cancel: cancel}
+}
+
+// Subscribe подписывает на изменения задачи id. В возвращаемый канал
+// приходит сигнал после каждого изменения задачи; несколько изменений подряд
+// могут объединяться в один сигнал, поэтому актуальное состояние следует
+// читать через GetTask. Функцию отписки необходимо вызвать, когда подписка
+// больше не нужна. Возвращает false, если задача неизвестна.
+func (m *Manager) Subscribe(id string) (<-chan struct{}, func(), bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.tasks[id]; !ok {
+		return nil, nil, false
+	}
+	ch := make(chan struct{}, 1)
+	if m.subs[id] == nil {
+		m.subs[id] = make(map[chan struct{}]struct{})
+	}
+	m.subs[id][ch] = struct{}{}
+	unsubscribe := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.subs[id], ch)
+		if len(m.subs[id]) == 0 {
+			delete(m.subs, id)
+		}
+	}
+	return ch, unsubscribe, true
+}
+
+// notify сигналит подписчикам задачи id об изменении. Отправка не блокирует:
+// если у подписчика уже есть необработанный сигнал, новый не нужен.
+// Вызывать под m.mu.
+func (m *Manager) notify(id string) {
+	for ch := range m.subs[id] {
+		select {
+		case ch <- struct{}{}:
+		default:
 		}
 	}
-	return t, nil
 }
 
 // enqueueJob помещает указанный файл в очередь на скачивание и помечает его
-// состояние как pending (ожидание), если это необходимо.
+// состояние как pending (ожидание), если это необходимо. Если очередь
+// заполнена, вызов блокируется до освобождения места, но m.mu при этом не
+// удерживается: воркеры и чтение статусов продолжают работать.
 func (m *Manager) enqueueJob(taskID string, fileIndex int) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	task, ok := m.tasks[taskID]
-	if !ok || fileIndex < 0 || fileIndex >= len(task.Files) {
+	if !ok || fileIndex < 0 || fileIndex >= len(task.Files) || task.Files[fileIndex].Status == model.StatusCompleted {
+		m.mu.Unlock()
 		return
 	}
-	if task.Files[fileIndex].Status != "completed" {
-		task.Files[fileIndex].Status = "pending"
-		task.UpdatedAt = time.Now().UTC()
-		m.jobs <- Job{TaskID: taskID, FileIndex: fileIndex}
-	}
+	task.Files[fileIndex].Status = model.StatusPending
+	m.stopSpeed(Job{TaskID: taskID, FileIndex: fileIndex}, &task.Files[fileIndex])
+	task.UpdatedAt = time.Now().UTC()
+	priority := task.Priority
+	m.mu.Unlock()
+	m.queue.push(priority, Job{TaskID: taskID, FileIndex: fileIndex})
+}
+
+// StartDraining переводит менеджер в режим draining: новые задачи
+// принимаются, но их файлы не ставятся в очередь. Вызывается в начале
+// корректного завершения работы.
+func (m *Manager) StartDraining() {
+	m.mu.Lock()
+	m.draining = true
+	m.mu.Unlock()
+}
+
+// Draining сообщает, находится ли менеджер в режиме остановки.
+func (m *Manager) Draining() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.draining
+}
+
+// QueueDepth возвращает число заданий, ожидающих в очереди.
+func (m *Manager) QueueDepth() int {
+	return m.queue.len()
 }
 
 // GetTask возвращает копию задачи по ID, если она существует. Возвращает вторым
@@ -101,196 +883,889 @@ func (m *Manager) GetTask(id string) (*model.Task, bool) {
 		return nil, false
 	}
 	// return a deep copy to avoid exposing internal pointers
-	copyTask := *t
-	copyTask.Files = make([]model.FileState, len(t.Files))
-	copy(copyTask.Files, t.Files)
-	return &copyTask, true
+	return copyTask(t), true
+}
+
+// copyTask возвращает копию задачи с отдельным срезом файлов.
+func copyTask(t *model.Task) *model.Task {
+	c := *t
+	c.Files = make([]model.FileState, len(t.Files))
+	copy(c.Files, t.Files)
+	c.Labels = maps.Clone(t.Labels)
+	return &c
+}
+
+// ErrFileNotFound возвращается, если файл задачи не существует или ещё не
+// скачан.
+var ErrFileNotFound = errors.New("file not found")
+
+// ErrInvalidDestSubdir возвращается, если каталог назначения задачи
+// абсолютный или выходит за пределы каталога загрузок.
+var ErrInvalidDestSubdir = errors.New("dest_subdir must be a relative path inside the download directory")
+
+// cleanSubdir проверяет и нормализует каталог назначения задачи. Пустая
+// строка допустима и означает каталог с ID задачи.
+func cleanSubdir(dir string) (string, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return "", nil
+	}
+	if strings.ContainsRune(dir, '\\') || !filepath.IsLocal(filepath.FromSlash(dir)) {
+		return "", ErrInvalidDestSubdir
+	}
+	dir = path.Clean(dir)
+	if dir == "." {
+		return "", ErrInvalidDestSubdir
+	}
+	return dir, nil
+}
+
+// taskDir возвращает каталог файлов задачи относительно каталога загрузок:
+// DestSubdir, если он задан, иначе ID задачи или, при LayoutFlatPrefixed,
+// сам каталог загрузок (".").
+func taskDir(t *model.Task) string {
+	if t.DestSubdir != "" {
+		return filepath.FromSlash(t.DestSubdir)
+	}
+	if flat(t) {
+		return "."
+	}
+	return t.ID
 }
 
-// StartWorkers запускает n воркеров, которые читают из канала jobs и скачивают
-// файлы, пока контекст ctx не будет отменён. Воркеры учитываются в wait group,
+// filePath возвращает путь, по которому сохраняется файл задачи t.
+func (m *Manager) filePath(t *model.Task, f model.FileState) string {
+	return filepath.Join(m.cfg.DownloadDir, fileRelPath(t, f))
+}
+
+// FilePath возвращает путь к скачанному файлу с индексом index задачи id.
+// Возвращает ErrTaskNotFound для неизвестной задачи и ErrFileNotFound, если
+// индекс вне диапазона или файл ещё не скачан. Путь гарантированно лежит
+// внутри каталога загрузок.
+func (m *Manager) FilePath(id string, index int) (string, error) {
+	m.mu.RLock()
+	task, ok := m.tasks[id]
+	if !ok {
+		m.mu.RUnlock()
+		return "", ErrTaskNotFound
+	}
+	if index < 0 || index >= len(task.Files) || task.Files[index].Status != model.StatusCompleted {
+		m.mu.RUnlock()
+		return "", ErrFileNotFound
+	}
+	p := m.filePath(task, task.Files[index])
+	m.mu.RUnlock()
+	rel, err := filepath.Rel(m.cfg.DownloadDir, p)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrFileNotFound
+	}
+	return p, nil
+}
+
+// StartWorkers запускает n воркеров, которые забирают задания из очереди (с
+// учётом приоритета) и скачивают файлы, пока контекст ctx не будет отменён. Воркеры учитываются в wait group,
 // которая увеличивается при начале скачивания и уменьшается по завершению.
-func (m *Manager) StartWorkers(ctx context.Context, n int, downloadDir string) {
-	for i := 0; i < n; i++ {
+// Файлы, восстановленные LoadFromSnapshot, ставятся в очередь фоновой
+// горутиной по мере освобождения места, распределённо по окну
+// Config.RestoreJitter, если оно задано.
+func (m *Manager) StartWorkers(ctx context.Context, n int) {
+	m.mu.Lock()
+	restored := m.restored
+	m.restored = nil
+	m.workers += n
+	m.running += n
+	m.workerCtx = ctx
+	m.mu.Unlock()
+	if len(restored) > 0 {
 		go func() {
-			for {
-				select {
-				case <-ctx.Done():
+			start := time.Now()
+			delays := restoreDelays(len(restored), m.cfg.RestoreJitter)
+			for i, q := range restored {
+				if delays != nil && !sleepUntil(ctx, start.Add(delays[i])) {
+					return
+				}
+				if !m.queue.pushContext(ctx, q.priority, q.job) {
 					return
-				case job := <-m.jobs:
-					m.processJob(ctx, job, downloadDir)
 				}
 			}
+			slog.Info("restored jobs queued", "jobs", len(restored))
 		}()
 	}
+	for i := 0; i < n; i++ {
+		m.wg.Add(1)
+		go m.runWorker(ctx)
+	}
+}
+
+// runWorker забирает задания из очереди и скачивает их, пока не будет
+// отменён ctx или воркеров не станет больше целевого числа (см. SetWorkers).
+func (m *Manager) runWorker(ctx context.Context) {
+	defer m.wg.Done()
+	for {
+		shrink, ok := m.keepWorker(ctx)
+		if !ok {
+			return
+		}
+		if job, ok := m.queue.pop(ctx, shrink); ok {
+			m.processJob(ctx, job)
+		}
+	}
+}
+
+// keepWorker решает, продолжать ли работу воркеру: лишний воркер снимается
+// с учёта в running и должен завершиться. Иначе возвращает канал, закрытие
+// которого сообщает об уменьшении пула.
+func (m *Manager) keepWorker(ctx context.Context) (<-chan struct{}, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ctx.Err() != nil || m.running > m.workers {
+		m.running--
+		return nil, false
+	}
+	return m.shrink, true
 }
 
 // processJob выполняет скачивание конкретного файла. Он устанавливает статус
-// файла "in‑progress", скачивает его, после чего помечает "completed" или
+// файла "in-progress", скачивает его, после чего помечает "completed" или
 // "error". Также пересчитывает общий статус задачи после завершения всех
 // файлов.
-func (m *Manager) processJob(ctx context.Context, job Job, downloadDir string) {
+func (m *Manager) processJob(ctx context.Context, job Job) {
 	m.mu.Lock()
 	task, ok := m.tasks[job.TaskID]
 	if !ok {
 		m.mu.Unlock()
 		return
 	}
-	if job.FileIndex < 0 || job.FileIndex >= len(task.Files) || task.Files[job.FileIndex].Status == "completed" {
+	// only pending files are processed: a file may be queued more than once
+	// (e.g. after pause/resume), and the duplicate job must be skipped
+	if job.FileIndex < 0 || job.FileIndex >= len(task.Files) || task.Files[job.FileIndex].Status != model.StatusPending {
 		m.mu.Unlock()
 		return
 	}
 
-	task.Files[job.FileIndex].Status = "in‑progress"
-	task.UpdatedAt = time.Now().UTC()
-	task.Status = "in‑progress"
+	taskCtx := m.controls[job.TaskID].ctx
+	if taskCtx.Err() != nil {
+		m.mu.Unlock()
+		// a paused task keeps its files pending until resumed
+		if !pausedBy(taskCtx) {
+			m.updateFileState(job.TaskID, job.FileIndex, abortStatus(taskCtx), context.Cause(taskCtx).Error())
+		}
+		return
+	}
+
+	if !m.acquireSlot(task, job) {
+		m.mu.Unlock()
+		return
+	}
+
+	now := time.Now().UTC()
+	file := &task.Files[job.FileIndex]
+	file.Status = model.StatusInProgress
+	// RetryCount растёт только при автоматических повторах (см. countRetry):
+	// пауза, перезапуск сервиса и ручной retry повторами не считаются
+	file.LastAttemptAt = &now
+	file.ExtractStatus, file.ExtractDir = "", ""
+	file.Path = filepath.ToSlash(fileRelPath(task, *file))
+	task.UpdatedAt = now
+	task.Status = model.StatusInProgress
+	fileURL, dest, headers, authURLs := file.URL, m.filePath(task, *file), file.Headers, file.AuthURLs
+	extract, expectedType, checksum := task.Extract, file.ExpectedContentType, file.SHA256
+	accept := file.Accept
+	etag, lastModified, partValidator := file.ETag, file.LastModified, file.PartValidator
+	candidates := append([]string{file.URL}, file.Mirrors...)
+	m.active[job] = now
+	span := startFileSpan(task, job.FileIndex)
+	m.notify(job.TaskID)
 	m.mu.Unlock()
+	defer m.endFileSpan(span, job)
+	defer m.finishActive(job)
+	defer m.releaseSlot(job.TaskID)
+	defer m.releaseDisk(job)
+	m.persistTask(job.TaskID)
 
-	m.wg.Add(1)
-	defer m.wg.Done()
+	metrics.ActiveWorkers.Inc()
+	defer metrics.ActiveWorkers.Dec()
 
-	fileURL := task.Files[job.FileIndex].URL
-	dir := filepath.Join(downloadDir, job.TaskID)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		m.updateFileState(job.TaskID, job.FileIndex, "error", err.Error())
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		slog.Warn("cannot create download directory", "task_id", job.TaskID, "file_index", job.FileIndex, "error", err)
+		m.updateFileState(job.TaskID, job.FileIndex, model.StatusError, "cannot create directory: "+err.Error())
 		return
 	}
-	filename := download.DeriveFileName(fileURL, job.FileIndex)
-	dest := filepath.Join(dir, filename)
-	// download
-	if err := download.DownloadWithContext(ctx, fileURL, dest); err != nil {
-		m.updateFileState(job.TaskID, job.FileIndex, "error", err.Error())
+	slog.Info("download started", "task_id", job.TaskID, "file_index", job.FileIndex, "url", fileURL)
+	// download, bounded by the per-file timeout and canceled together with
+	// either the root context or the task context
+	dlCtx, cancel := context.WithTimeoutCause(ctx, m.cfg.DownloadTimeout, context.DeadlineExceeded)
+	stop := context.AfterFunc(taskCtx, cancel)
+	opts := m.downloadOptions()
+	opts.Headers = headers
+	opts.ExpectedContentType = expectedType
+	opts.Accept = accept
+	// файл уже скачан раньше (например, повтор после ошибки распаковки):
+	// условный запрос позволяет не скачивать его заново
+	if _, err := os.Stat(dest); err == nil {
+		opts.IfNoneMatch, opts.IfModifiedSince = etag, lastModified
+	}
+	opts.ResumeValidator = partValidator
+	opts.Progress = func(n int64) { m.addProgress(job, n) }
+	opts.OnResponse = func(info download.Info) {
+		m.recordInfo(job, info)
+		m.recordPartValidator(job, info.Validator)
+	}
+	opts.Reserve = func(size int64) error { return m.reserveDisk(job, size) }
+	source, res, err := m.downloadFirst(dlCtx, job, candidates, authURLs, dest, opts)
+	stop()
+	cancel()
+	if err != nil && pausedBy(taskCtx) {
+		slog.Info("download paused", "task_id", job.TaskID, "file_index", job.FileIndex,
+			"url", fileURL, "status", model.StatusPending)
+		m.requeuePaused(job.TaskID, job.FileIndex)
+	} else if err != nil && taskCtx.Err() != nil {
+		msg, status := context.Cause(taskCtx).Error(), abortStatus(taskCtx)
+		slog.Info("download canceled", "task_id", job.TaskID, "file_index", job.FileIndex,
+			"url", fileURL, "status", status, "error", msg)
+		m.updateFileState(job.TaskID, job.FileIndex, status, msg)
+	} else if err != nil {
+		msg := m.downloadError(ctx, dlCtx, err)
+		slog.Warn("download failed", "task_id", job.TaskID, "file_index", job.FileIndex,
+			"url", fileURL, "status", model.StatusError, "error", msg)
+		m.updateFileState(job.TaskID, job.FileIndex, model.StatusError, msg)
 	} else {
-		m.updateFileState(job.TaskID, job.FileIndex, "completed", "")
+		slog.Info("download completed", "task_id", job.TaskID, "file_index", job.FileIndex,
+			"url", source, "status", model.StatusCompleted)
+		m.recordResult(job, source, res)
+		if checksum != "" {
+			if err := verifyChecksum(dest, checksum); err != nil {
+				slog.Warn("checksum verification failed", "task_id", job.TaskID, "file_index", job.FileIndex,
+					"status", model.StatusError, "error", err)
+				m.updateFileState(job.TaskID, job.FileIndex, model.StatusError, err.Error())
+				return
+			}
+		}
+		if extract {
+			if err := m.extractArchive(ctx, job, dest); err != nil {
+				slog.Warn("extraction failed", "task_id", job.TaskID, "file_index", job.FileIndex,
+					"status", model.StatusError, "error", err)
+				m.updateFileState(job.TaskID, job.FileIndex, model.StatusError, "extract: "+err.Error())
+				return
+			}
+		}
+		m.updateFileState(job.TaskID, job.FileIndex, model.StatusCompleted, "")
 	}
 }
 
+// downloadFirst скачивает файл с первого доступного URL из candidates
+// (основной URL и зеркала), пробуя их по очереди. Любая ошибка скачивания,
+// включая неуспешный статус ответа, переводит к следующему кандидату; отмена
+// или таймаут ctx прекращает перебор. Возвращает URL, с которого скачан файл.
+// Если не удалось ни с одного, ошибка перечисляет причины для каждого URL.
+// При включённом HeadPreflight перед каждой передачей выполняется HEAD.
+// Временные ошибки повторяются на том же URL (см. downloadRetry), прежде
+// чем перейти к следующему кандидату. authURLs сопоставляет кандидатам без учётных данных исходные URL с ними.
+func (m *Manager) downloadFirst(ctx context.Context, job Job, candidates []string, authURLs map[string]string, dest string, opts download.Options) (string, download.Result, error) {
+	var failures []string
+	var err error
+	for _, u := range candidates {
+		var res download.Result
+		// u без учётных данных попадает в логи и состояние файла, а запрос
+		// отправляется по исходному URL
+		reqURL := u
+		if raw, ok := authURLs[u]; ok {
+			reqURL = raw
+		}
+		res, err = m.downloadRetry(ctx, job, u, reqURL, dest, opts)
+		if err == nil {
+			return u, res, nil
+		}
+		if ctx.Err() != nil || len(candidates) == 1 {
+			return u, res, err
+		}
+		slog.Warn("mirror failed", "url", u, "error", err)
+		failures = append(failures, fmt.Sprintf("%s: %v", u, err))
+	}
+	return "", download.Result{}, fmt.Errorf("all mirrors failed: %s", strings.Join(failures, "; "))
+}
+
+// preflight выполняет HEAD-запрос, если он включён, и сохраняет размер и
+// тип содержимого в состоянии файла. Ошибкой считается только превышение
+// лимитов; если HEAD не поддерживается или не удался, скачивание продолжается
+// обычным GET.
+func (m *Manager) preflight(ctx context.Context, job Job, fileURL, dest string, opts download.Options) error {
+	if !m.cfg.HeadPreflight {
+		return nil
+	}
+	info, err := m.downloaders.Preflight(ctx, fileURL, dest, opts)
+	limited := errors.Is(err, download.ErrTooLarge) || errors.Is(err, download.ErrInsufficientDiskSpace)
+	if err != nil && !limited {
+		slog.Debug("preflight skipped", "task_id", job.TaskID, "file_index", job.FileIndex,
+			"url", fileURL, "error", err)
+		return nil
+	}
+	m.recordInfo(job, info)
+	return err
+}
+
+// recordInfo сохраняет в состоянии файла размер и тип содержимого, известные
+// до передачи тела.
+func (m *Manager) recordInfo(job Job, info download.Info) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if task, ok := m.tasks[job.TaskID]; ok && job.FileIndex >= 0 && job.FileIndex < len(task.Files) {
+		f := &task.Files[job.FileIndex]
+		if info.Size >= 0 {
+			f.Size = info.Size
+		}
+		if info.ContentType != "" {
+			f.ContentType = info.ContentType
+		}
+		// байты, скачанные прошлой попыткой, засчитываются продолженной
+		if info.Resumed > 0 {
+			f.Downloaded = info.Resumed
+		}
+		m.notify(job.TaskID)
+	}
+}
+
+// recordPartValidator запоминает валидатор ответа, который пишется во
+// временный файл, чтобы следующая попытка могла продолжить скачивание с
+// If-Range. Валидатор сохраняется в снапшоте вместе с задачей.
+func (m *Manager) recordPartValidator(job Job, validator string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if task, ok := m.tasks[job.TaskID]; ok && job.FileIndex >= 0 && job.FileIndex < len(task.Files) {
+		task.Files[job.FileIndex].PartValidator = validator
+	}
+}
+
+// addProgress учитывает n скачанных байт файла. Подписчики не уведомляются:
+// событие на каждую запись было бы слишком частым.
+func (m *Manager) addProgress(job Job, n int64) {
+	metrics.BytesDownloaded.Add(float64(n))
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if task, ok := m.tasks[job.TaskID]; ok && job.FileIndex >= 0 && job.FileIndex < len(task.Files) {
+		f := &task.Files[job.FileIndex]
+		f.Downloaded += n
+		m.sampleSpeed(job, f, time.Now())
+	}
+}
+
+// resetProgress обнуляет счётчик скачанных байт перед новой попыткой.
+func (m *Manager) resetProgress(job Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if task, ok := m.tasks[job.TaskID]; ok && job.FileIndex >= 0 && job.FileIndex < len(task.Files) {
+		task.Files[job.FileIndex].Downloaded = 0
+		m.startSpeed(job, &task.Files[job.FileIndex], time.Now())
+	}
+}
+
+// downloadOptions собирает параметры скачивания из конфигурации менеджера.
+func (m *Manager) downloadOptions() download.Options {
+	return download.Options{
+		IdleTimeout:    m.cfg.IdleTimeout,
+		UserAgent:      m.cfg.UserAgent,
+		DefaultHeaders: m.cfg.DefaultHeaders,
+		MaxBytes:       m.cfg.MaxFileSize,
+		CheckDiskSpace: m.cfg.CheckDiskSpace,
+		MinFreeBytes:   m.cfg.MinFreeDisk,
+
+		MaxRedirects:          m.cfg.MaxRedirects,
+		SameHostRedirects:     m.cfg.SameHostRedirects,
+		HostPolicy:            m.cfg.HostPolicy,
+		BlockPrivateIPs:       !m.cfg.AllowPrivateIPs,
+		DecodeContentEncoding: !m.cfg.KeepContentEncoding,
+
+		AllowMissingContentType: !m.cfg.RequireContentType,
+		Client:                  m.client,
+		Redactor:                m.redactor,
+		RejectHTML:              m.cfg.RejectHTMLPages,
+		TLSConfig:               m.tlsConfig(),
+		S3:                      m.s3,
+		Chunks:                  m.cfg.DownloadChunks,
+		ChunkMinSize:            m.cfg.ChunkMinSize,
+		Resume:                  m.cfg.ResumeDownloads,
+		ConnectTimeout:          m.cfg.ConnectTimeout,
+		Conns:                   m.conns,
+	}
+}
+
+// tlsConfig возвращает параметры TLS для источников, скачиваемых не через
+// HTTP-клиент (FTPS), с теми же корневыми сертификатами и проверкой, что у
+// транспорта. nil — системные настройки.
+func (m *Manager) tlsConfig() *tls.Config {
+	if m.cfg.RootCAs == nil && !m.cfg.TLSInsecureSkipVerify {
+		return nil
+	}
+	return &tls.Config{
+		RootCAs:            m.cfg.RootCAs,
+		InsecureSkipVerify: m.cfg.TLSInsecureSkipVerify,
+	}
+}
+
+// recordResult сохраняет в состоянии файла сведения об успешном скачивании:
+// использованное зеркало (source) и итоговый URL после редиректов.
+func (m *Manager) recordResult(job Job, source string, res download.Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	task, ok := m.tasks[job.TaskID]
+	if !ok || job.FileIndex < 0 || job.FileIndex >= len(task.Files) {
+		return
+	}
+	f := &task.Files[job.FileIndex]
+	f.SourceURL, f.FinalURL, f.PartValidator = "", "", ""
+	if source != f.URL {
+		f.SourceURL = source
+	}
+	// итоговый URL (например, presigned-ссылка после редиректа) может
+	// содержать секреты
+	if final := m.redactor.URL(res.FinalURL); final != source {
+		f.FinalURL = final
+	}
+	f.Size = res.Size
+	f.Downloaded = res.Size
+	if res.NotModified {
+		// ответ 304 может не содержать заголовков исходного ответа
+		if res.ContentType != "" {
+			f.ContentType = res.ContentType
+		}
+		if res.ETag != "" {
+			f.ETag = res.ETag
+		}
+		if res.LastModified != "" {
+			f.LastModified = res.LastModified
+		}
+		return
+	}
+	f.ContentType = res.ContentType
+	f.ETag, f.LastModified = res.ETag, res.LastModified
+}
+
+// downloadError формирует сообщение об ошибке скачивания. Отмена корневого
+// контекста (остановка сервиса) и истечение таймаута файла получают разные
+// сообщения, чтобы их можно было отличить в статусе файла, а ошибки записи
+// на диск помечаются, чтобы их не приняли за ошибки сети.
+func (m *Manager) downloadError(ctx, dlCtx context.Context, err error) string {
+	switch {
+	case ctx.Err() != nil:
+		return "download canceled: service is shutting down"
+	case errors.Is(context.Cause(dlCtx), context.DeadlineExceeded):
+		return fmt.Sprintf("download timeout exceeded (%s)", m.cfg.DownloadTimeout)
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return "cannot write file: " + err.Error()
+	}
+	return err.Error()
+}
+
 // updateFileState обновляет статус и сообщение об ошибке файла и
 // пересчитывает общий статус задачи (учитывает наличие ошибок и завершение
-// всех скачиваний).
-func (m *Manager) updateFileState(taskID string, index int, status, errMsg string) {
+// всех скачиваний). Файл, уже получивший итоговый статус, не меняется.
+func (m *Manager) updateFileState(taskID string, index int, status model.Status, errMsg string) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	task, ok := m.tasks[taskID]
 	if !ok || index < 0 || index >= len(task.Files) {
+		m.mu.Unlock()
+		return
+	}
+	// файл уже получил итоговый статус при отмене задачи (abortTask), пока
+	// воркер его скачивал
+	if fileTerminal(task.Files[index].Status) {
+		m.mu.Unlock()
 		return
 	}
+	if status == model.StatusCompleted {
+		m.diskUsage += task.Files[index].Size
+	}
 	task.Files[index].Status = status
 	task.Files[index].Error = errMsg
+	m.stopSpeed(Job{TaskID: taskID, FileIndex: index}, &task.Files[index])
+	if fileTerminal(status) {
+		m.notifyFile(task, index)
+	}
 	task.UpdatedAt = time.Now().UTC()
-	//status
+	switch status {
+	case model.StatusCompleted:
+		metrics.FilesDownloaded.Inc()
+	case model.StatusError:
+		metrics.FilesFailed.Inc()
+	}
+	prev := task.Status
+	m.recomputeStatus(task)
+	var finished *model.Task
+	m.notify(taskID)
+	done := IsTerminal(task.Status) && !IsTerminal(prev)
+	if done {
+		m.endTaskSpan(task)
+	}
+	if done && task.CallbackURL != "" {
+		finished = copyTask(task)
+	}
+	m.mu.Unlock()
+	m.persistTask(taskID)
+	if finished != nil {
+		go m.notifyCompletion(finished)
+	}
+	if done {
+		m.evictTasks()
+	}
+}
+
+// IsTerminal сообщает, является ли статус задачи окончательным.
+func IsTerminal(status model.Status) bool {
+	switch status {
+	case model.StatusCompleted, model.StatusCompletedWithErrors, model.StatusCanceled:
+		return true
+	}
+	return false
+}
+
+// completionPayload — тело уведомления о завершении задачи.
+type completionPayload struct {
+	TaskID string            `json:"task_id"`
+	Status model.Status      `json:"status"`
+	Files  []model.FileState `json:"files"`
+}
+
+// notifyCompletion отправляет уведомление о завершении задачи на её
+// CallbackURL. Неудачная доставка только логируется и не влияет на задачу.
+func (m *Manager) notifyCompletion(t *model.Task) {
+	payload := completionPayload{TaskID: t.ID, Status: t.Status, Files: t.Files}
+	if err := m.webhooks.Send(context.Background(), t.CallbackURL, payload); err != nil {
+		slog.Warn("task callback failed", "task_id", t.ID, "url", m.redactor.URL(t.CallbackURL), "error", m.redactor.Error(err))
+		return
+	}
+	slog.Info("task callback delivered", "task_id", t.ID, "url", m.redactor.URL(t.CallbackURL), "status", t.Status)
+}
+
+// fileTerminal сообщает, является ли статус файла окончательным.
+func fileTerminal(status model.Status) bool {
+	switch status {
+	case model.StatusCompleted, model.StatusError, model.StatusCanceled:
+		return true
+	}
+	return false
+}
+
+// fileCallbackPayload — тело уведомления о завершении файла.
+type fileCallbackPayload struct {
+	TaskID    string       `json:"task_id"`
+	FileIndex int          `json:"file_index"`
+	URL       string       `json:"url"`
+	Status    model.Status `json:"status"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// notifyFile отправляет в фоне уведомление о завершении файла index задачи t
+// на её FileCallbackURL, если он задан. Как и уведомление о задаче, доставка
+// повторяется при ошибках, а неудача только логируется. Вызывать под m.mu.
+func (m *Manager) notifyFile(t *model.Task, index int) {
+	if t.FileCallbackURL == "" {
+		return
+	}
+	f := &t.Files[index]
+	callbackURL := t.FileCallbackURL
+	payload := fileCallbackPayload{TaskID: t.ID, FileIndex: index, URL: f.URL, Status: f.Status, Error: f.Error}
+	go func() {
+		if err := m.webhooks.Send(context.Background(), callbackURL, payload); err != nil {
+			slog.Warn("file callback failed", "task_id", payload.TaskID, "file_index", index,
+				"url", m.redactor.URL(callbackURL), "error", m.redactor.Error(err))
+			return
+		}
+		slog.Info("file callback delivered", "task_id", payload.TaskID, "file_index", index,
+			"url", m.redactor.URL(callbackURL), "status", payload.Status)
+	}()
+}
+
+// checkCallbackURL проверяет, что raw — пустая строка или абсолютный
+// http(s) URL; name — имя поля для сообщения об ошибке.
+func checkCallbackURL(name, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New(name + " must be an absolute http(s) URL")
+	}
+	return nil
+}
+
+// recomputeStatus пересчитывает общий статус задачи по статусам файлов.
+// Задача завершена, когда все файлы в терминальном состоянии (completed,
+// error или canceled): если есть отменённые файлы — "canceled", если есть
+// ошибки — "completed_with_errors", иначе — "completed". Незавершённая
+// задача на паузе получает статус "paused", а ждущая отложенного запуска —
+// "scheduled". Поддерживает счётчик незавершённых задач m.unfinished.
+// Вызывать под m.mu.
+func (m *Manager) recomputeStatus(task *model.Task) {
+	wasActive := !IsTerminal(task.Status)
+	defer func() {
+		if active := !IsTerminal(task.Status); active != wasActive {
+			if active {
+				m.unfinished++
+			} else {
+				m.unfinished--
+			}
+		}
+	}()
 	allDone := true
 	anyErrors := false
+	anyCanceled := false
 	for _, f := range task.Files {
-		if f.Status != "completed" {
-			allDone = false
-		}
-		if f.Status == "error" {
+		switch f.Status {
+		case model.StatusCompleted:
+		case model.StatusError:
 			anyErrors = true
+		case model.StatusCanceled:
+			anyCanceled = true
+		default:
+			allDone = false
 		}
 	}
-	if allDone {
-		if anyErrors {
-			task.Status = "completed_with_errors"
-		} else {
-			task.Status = "completed"
-		}
-	} else {
-		task.Status = "in‑progress"
+	switch {
+	case !allDone && task.Paused:
+		task.Status = model.StatusPaused
+	case !allDone && task.StartAt != nil:
+		task.Status = model.StatusScheduled
+	case !allDone:
+		task.Status = model.StatusInProgress
+	case anyCanceled:
+		task.Status = model.StatusCanceled
+	case anyErrors:
+		task.Status = model.StatusCompletedWithErrors
+	default:
+		task.Status = model.StatusCompleted
 	}
 }
 
-// SnapshotLoop периодически записывает текущее состояние задач в JSON‑файл.
+// SnapshotLoop периодически записывает текущее состояние задач в хранилище.
 // Работает до отмены контекста. Использует копию данных для серилизации,
-// чтобы не блокировать обновления.
-func (m *Manager) SnapshotLoop(ctx context.Context, filePath string, interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+// чтобы не блокировать обновления. Если хранилище сохраняет задачи поштучно
+// (store.TaskStore) или interval <= 0, периодическая запись пропускается и
+// выполняется только финальная. Чтобы финальный снапшот содержал итоговые
+// статусы прерванных скачиваний, ctx нужно отменять после Wait.
+func (m *Manager) SnapshotLoop(ctx context.Context, interval time.Duration) {
+	_, incremental := m.store.(store.TaskStore)
+	var tick <-chan time.Time
+	if interval > 0 && !incremental {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			// perform a final snapshot before exit
-			m.writeSnapshot(filePath)
+			m.writeSnapshot()
 			return
-		case <-ticker.C:
-			m.writeSnapshot(filePath)
+		case <-tick:
+			m.writeSnapshot()
 		}
 	}
 }
 
-// writeSnapshot сериализует все задачи в JSON и записывает их в указанный файл.
-// Сначала создаёт временный файл, затем атомарно переименовывает его, чтобы
-// избежать повреждения данных.
-func (m *Manager) writeSnapshot(filePath string) {
+// Snapshot немедленно записывает состояние всех задач в хранилище, не
+// дожидаясь SnapshotLoop. Возвращает число записанных задач.
+func (m *Manager) Snapshot() (int, error) {
+	if m.store == nil {
+		return 0, nil
+	}
+	m.snapshotMu.Lock()
+	defer m.snapshotMu.Unlock()
 	m.mu.RLock()
 	// make a deep copy for serialization
 	tasksCopy := make(map[string]*model.Task, len(m.tasks))
 	for id, t := range m.tasks {
-		taskCopy := *t
-		taskCopy.Files = make([]model.FileState, len(t.Files))
-		copy(taskCopy.Files, t.Files)
-		tasksCopy[id] = &taskCopy
+		tasksCopy[id] = copyTask(t)
 	}
 	m.mu.RUnlock()
-	data, err := json.MarshalIndent(tasksCopy, "", "  ")
+	if err := m.store.Save(tasksCopy); err != nil {
+		return 0, err
+	}
+	return len(tasksCopy), nil
+}
+
+// writeSnapshot записывает снапшот и логирует результат.
+func (m *Manager) writeSnapshot() {
+	n, err := m.Snapshot()
 	if err != nil {
-		log.Printf("snapshot marshal error: %v", err)
+		slog.Error("snapshot write error", "error", err)
 		return
 	}
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		log.Printf("snapshot directory error: %v", err)
+	slog.Debug("snapshot written", "tasks", n)
+}
+
+// persistTask сразу сохраняет задачу id, если хранилище поддерживает
+// поштучную запись. Вызывать без удержания m.mu.
+func (m *Manager) persistTask(id string) {
+	ts, ok := m.store.(store.TaskStore)
+	if !ok {
 		return
 	}
-	tmp := filePath + ".tmp"
-	if err := os.WriteFile(tmp, data, 0o644); err != nil {
-		log.Printf("snapshot write error: %v", err)
+	m.persistMu.Lock()
+	defer m.persistMu.Unlock()
+	m.mu.RLock()
+	t, ok := m.tasks[id]
+	if !ok {
+		m.mu.RUnlock()
 		return
 	}
-	if err := os.Rename(tmp, filePath); err != nil {
-		log.Printf("snapshot rename error: %v", err)
-		return
+	c := copyTask(t)
+	m.mu.RUnlock()
+	if err := ts.SaveTask(c); err != nil {
+		slog.Error("task persist error", "task_id", id, "error", err)
 	}
 }
 
-// LoadFromSnapshot читает задачи из снапшота и загружает их в менеджер.
-// Все файлы со статусами "pending", "in‑progress" или "error" помещаются
-// обратно в очередь на скачивание. Вызывать до запуска воркеров.
-func (m *Manager) LoadFromSnapshot(filePath, downloadDir string) {
-	f, err := os.Open(filePath)
+// LoadFromSnapshot читает задачи из хранилища и загружает их в менеджер.
+// Все файлы со статусами "pending", "in-progress" или "error" помещаются
+// обратно в очередь на скачивание; файлы задач на паузе лишь возвращаются в
+// "pending" и ждут ResumeTask. Сами задания ставятся в очередь уже после
+// запуска воркеров (см. StartWorkers), поэтому число восстановленных файлов
+// может превышать ёмкость очереди. Задачи ставятся в очередь в порядке
+// создания, поэтому внутри каждого приоритета сохраняется исходный порядок.
+// Записи, которые нельзя загрузить без потери других задач, пропускаются с
+// предупреждением в логе: пустые, с ID, не совпадающим с ключом в
+// хранилище, и с ID уже загруженной задачи. Возвращает число пропущенных
+// записей, включая пропущенные самим хранилищем (store.SkipReporter).
+// Если хранилище не удалось прочитать (например, снапшот повреждён),
+// возвращается ошибка, а менеджер остаётся без задач: продолжать ли работу,
+// решает вызывающий. Отсутствие сохранённого состояния ошибкой не является.
+// Вызывать до запуска воркеров.
+func (m *Manager) LoadFromSnapshot() (skipped int, err error) {
+	if m.store == nil {
+		return 0, nil
+	}
+	tasks, err := m.store.Load()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return
-		}
-		log.Printf("error opening snapshot: %v", err)
-		return
+		return 0, err
 	}
-	defer f.Close()
-	var tasks map[string]*model.Task
-	if err := json.NewDecoder(f).Decode(&tasks); err != nil {
-		log.Printf("snapshot decode error: %v", err)
-		return
+	if r, ok := m.store.(store.SkipReporter); ok {
+		skipped = r.Skipped()
 	}
 	now := time.Now().UTC()
+	ordered := make([]*model.Task, 0, len(tasks))
+	m.mu.RLock()
+	for key, task := range tasks {
+		if reason := snapshotSkipReason(key, task, m.tasks); reason != "" {
+			slog.Warn("snapshot task skipped", "key", key, "reason", reason)
+			skipped++
+			continue
+		}
+		ordered = append(ordered, task)
+	}
+	m.mu.RUnlock()
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
 	m.mu.Lock()
-	for id, task := range tasks {
+	for _, task := range ordered {
+		id := task.ID
 		m.tasks[id] = task
-		task.UpdatedAt = now
-		// queue files not completed
+		if p, err := normalizePriority(task.Priority); err == nil {
+			task.Priority = p
+		} else {
+			task.Priority = PriorityNormal
+		}
+		m.newTaskControl(id)
+		// имена выбираются при создании задачи; здесь — только для старых
+		// снапшотов без имён, по URL
+		_ = assignFileNames(task.Files, nil, id, task.CreatedAt)
+		// в старых снапшотах статус in-progress записан с неразрывным дефисом (U+2011)
+		task.Status = model.NormalizeStatus(task.Status)
+		if !IsTerminal(task.Status) {
+			m.unfinished++
+		}
+		for idx := range task.Files {
+			task.Files[idx].Status = model.NormalizeStatus(task.Files[idx].Status)
+		}
+		if task.IdempotencyKey != "" {
+			m.idemKeys[task.IdempotencyKey] = id
+		}
+		if task.URLSetHash == "" {
+			task.URLSetHash = urlSetHash(task.Files)
+		}
+		// в индексе остаётся новейшая задача с тем же набором URL
+		if prev, ok := m.tasks[m.urlIndex[task.URLSetHash]]; !ok || !prev.CreatedAt.After(task.CreatedAt) {
+			m.urlIndex[task.URLSetHash] = id
+		}
+		// queue files not completed; UpdatedAt of finished tasks is kept so
+		// that eviction order survives a restart
 		for idx, fs := range task.Files {
-			if fs.Status != "completed" {
-				task.Files[idx].Status = "pending"
+			if fs.Status != model.StatusCompleted && fs.Status != model.StatusCanceled {
+				task.UpdatedAt = now
+				if secretsLost(fs) {
+					task.Files[idx].Status = model.StatusError
+					task.Files[idx].Error = ErrSecretsLost.Error()
+					continue
+				}
+				task.Files[idx].Status = model.StatusPending
 				task.Files[idx].Error = ""
-				m.jobs <- Job{TaskID: id, FileIndex: idx}
+				// файлы отложенной задачи поставит в очередь ScheduleLoop
+				if !task.Paused && task.StartAt == nil {
+					m.restored = append(m.restored, queuedJob{task.Priority, Job{TaskID: id, FileIndex: idx}})
+				}
 			}
 		}
-		task.Status = "in‑progress"
+		m.diskUsage += taskDiskUsage(task)
+		// полностью скачанная задача остаётся завершённой
+		m.recomputeStatus(task)
+		m.armDeadline(task)
 	}
 	m.mu.Unlock()
+	m.evictTasks()
+	return skipped, nil
 }
 
-// Wait блокируется до завершения всех активных скачиваний. Обычно вызывается
-// во время корректного завершения работы, чтобы дождаться окончания задач.
-func (m *Manager) Wait() {
-	m.wg.Wait()
+// snapshotSkipReason возвращает причину, по которой задачу task с ключом key
+// из хранилища нельзя загрузить в loaded, или "", если можно.
+func snapshotSkipReason(key string, task *model.Task, loaded map[string]*model.Task) string {
+	switch {
+	case task == nil:
+		return "empty task entry"
+	case task.ID != key:
+		return fmt.Sprintf("task ID %q does not match its key", task.ID)
+	case loaded[key] != nil:
+		return "duplicate task ID"
+	}
+	return ""
+}
+
+// Wait блокируется до завершения всех воркеров или отмены ctx. Обычно
+// вызывается во время корректного завершения работы, после отмены контекста
+// воркеров, чтобы дождаться окончания активных скачиваний: к возврату true
+// прерванные файлы уже получили итоговый статус. Возвращает true, если
+// воркеры завершились, и false, если ctx истёк раньше.
+func (m *Manager) Wait(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// InFlight возвращает ID задач, у которых есть файлы в процессе скачивания.
+func (m *Manager) InFlight() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var ids []string
+	for id, t := range m.tasks {
+		for _, f := range t.Files {
+			if f.Status == model.StatusInProgress {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
 }
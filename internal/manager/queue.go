@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Приоритеты задач. Файлы задач с более высоким приоритетом выдаются
+// воркерам раньше; внутри одного приоритета порядок FIFO.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// ErrInvalidPriority возвращается AddTask для неизвестного приоритета.
+var ErrInvalidPriority = errors.New("priority must be high, normal or low")
+
+// normalizePriority проверяет приоритет и подставляет normal для пустого.
+func normalizePriority(p string) (string, error) {
+	switch p {
+	case "":
+		return PriorityNormal, nil
+	case PriorityHigh, PriorityNormal, PriorityLow:
+		return p, nil
+	}
+	return "", fmt.Errorf("%w, got %q", ErrInvalidPriority, p)
+}
+
+// jobQueue — очередь заданий с приоритетами: по буферизированному каналу на
+// каждый уровень. Воркеры забирают задания из канала с более высоким
+// приоритетом, пока он не пуст.
+type jobQueue struct {
+	high, normal, low chan Job
+}
+
+func newJobQueue(size int) *jobQueue {
+	return &jobQueue{
+		high:   make(chan Job, size),
+		normal: make(chan Job, size),
+		low:    make(chan Job, size),
+	}
+}
+
+// channel возвращает канал для приоритета p. Неизвестный приоритет
+// (например, из старого снапшота) считается обычным.
+func (q *jobQueue) channel(p string) chan Job {
+	switch p {
+	case PriorityHigh:
+		return q.high
+	case PriorityLow:
+		return q.low
+	}
+	return q.normal
+}
+
+// push ставит задание в очередь приоритета p, блокируясь, если она заполнена.
+func (q *jobQueue) push(p string, job Job) {
+	q.channel(p) <- job
+}
+
+// pushContext ставит задание в очередь, как push, но прекращает ожидание
+// после отмены ctx. Возвращает false, если задание не поставлено.
+func (q *jobQueue) pushContext(ctx context.Context, p string, job Job) bool {
+	select {
+	case q.channel(p) <- job:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// queuedJob — задание вместе с приоритетом его задачи.
+type queuedJob struct {
+	priority string
+	job      Job
+}
+
+// pop возвращает следующее задание с наибольшим доступным приоритетом,
+// блокируясь до его появления. Возвращает false после отмены ctx или при
+// получении сигнала из stop, если заданий нет.
+func (q *jobQueue) pop(ctx context.Context, stop <-chan struct{}) (Job, bool) {
+	select {
+	case job := <-q.high:
+		return job, true
+	default:
+	}
+	select {
+	case job := <-q.high:
+		return job, true
+	case job := <-q.normal:
+		return job, true
+	default:
+	}
+	select {
+	case <-ctx.Done():
+		return Job{}, false
+	case <-stop:
+		return Job{}, false
+	case job := <-q.high:
+		return job, true
+	case job := <-q.normal:
+		return job, true
+	case job := <-q.low:
+		return job, true
+	}
+}
+
+// len возвращает общее число заданий в очереди.
+func (q *jobQueue) len() int {
+	return len(q.high) + len(q.normal) + len(q.low)
+}
+
+// cap возвращает общую ёмкость очереди всех приоритетов.
+func (q *jobQueue) cap() int {
+	return cap(q.high) + cap(q.normal) + cap(q.low)
+}
@@ -0,0 +1,272 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"hh03012025/internal/model"
+	"hh03012025/internal/store"
+	"hh03012025/internal/util"
+)
+
+// TaskCount возвращает число задач, хранящихся в памяти.
+func (m *Manager) TaskCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.tasks)
+}
+
+// evictTasks удаляет самые давно обновлённые завершённые задачи, пока их
+// число превышает Config.MaxTasks. Незавершённые задачи не удаляются никогда,
+// поэтому при их избытке лимит может быть превышен.
+func (m *Manager) evictTasks() {
+	if m.cfg.MaxTasks <= 0 {
+		return
+	}
+	m.mu.RLock()
+	excess := len(m.tasks) - m.cfg.MaxTasks
+	if excess <= 0 {
+		m.mu.RUnlock()
+		return
+	}
+	type candidate struct {
+		id        string
+		updatedAt int64
+	}
+	var done []candidate
+	for id, t := range m.tasks {
+		if IsTerminal(t.Status) {
+			done = append(done, candidate{id, t.UpdatedAt.UnixNano()})
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(done, func(i, j int) bool { return done[i].updatedAt < done[j].updatedAt })
+	for _, c := range done[:min(excess, len(done))] {
+		if m.removeTask(c.id, m.cfg.DeleteEvictedFiles) {
+			slog.Info("task evicted", "task_id", c.id, "reason", "max tasks exceeded")
+		}
+	}
+}
+
+// ReapLoop периодически, раз в interval, удаляет задачи, завершённые более
+// Config.TaskTTL назад, вместе с их файлами. Работает до отмены ctx. При
+// нулевом TaskTTL сразу возвращается.
+func (m *Manager) ReapLoop(ctx context.Context, interval time.Duration) {
+	if m.cfg.TaskTTL <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.reapExpired(time.Now().UTC())
+		}
+	}
+}
+
+// reapExpired удаляет завершённые задачи, не обновлявшиеся дольше TaskTTL.
+func (m *Manager) reapExpired(now time.Time) {
+	m.mu.RLock()
+	var expired []string
+	for id, t := range m.tasks {
+		if IsTerminal(t.Status) && now.Sub(t.UpdatedAt) > m.cfg.TaskTTL {
+			expired = append(expired, id)
+		}
+	}
+	m.mu.RUnlock()
+	for _, id := range expired {
+		if m.removeTask(id, true) {
+			slog.Info("task expired", "task_id", id, "ttl", m.cfg.TaskTTL)
+		}
+	}
+}
+
+// removeTask удаляет завершённую задачу из памяти и хранилища, а при
+// deleteFiles — и её каталог с файлами. Статус проверяется повторно под
+// блокировкой, поэтому задача, возобновлённая после выбора кандидатов, не
+// удаляется. Возвращает false, если задачи нет или она ещё не завершена.
+func (m *Manager) removeTask(id string, deleteFiles bool) bool {
+	// persistMu гарантирует, что запоздалый persistTask не вернёт задачу в
+	// хранилище после удаления
+	m.persistMu.Lock()
+	defer m.persistMu.Unlock()
+	m.mu.Lock()
+	t, ok := m.tasks[id]
+	if !ok || !IsTerminal(t.Status) {
+		m.mu.Unlock()
+		return false
+	}
+	delete(m.tasks, id)
+	delete(m.slots, id)
+	m.diskUsage -= taskDiskUsage(t)
+	if ctl, ok := m.controls[id]; ok {
+		ctl.cancel(ErrTaskCanceled)
+		delete(m.controls, id)
+	}
+	if t.IdempotencyKey != "" && m.idemKeys[t.IdempotencyKey] == id {
+		delete(m.idemKeys, t.IdempotencyKey)
+	}
+	if m.urlIndex[t.URLSetHash] == id {
+		delete(m.urlIndex, t.URLSetHash)
+	}
+	// подписчики увидят, что задачи больше нет, и завершат поток
+	m.notify(id)
+	m.mu.Unlock()
+
+	if ts, ok := m.store.(store.TaskStore); ok {
+		if err := ts.DeleteTask(id); err != nil {
+			slog.Error("task delete error", "task_id", id, "error", err)
+		}
+	}
+	if deleteFiles {
+		m.removeTaskFiles(t)
+	}
+	return true
+}
+
+// removeTaskFiles удаляет файлы задачи с диска. Каталог с ID задачи
+// удаляется целиком, а в каталоге DestSubdir или общем каталоге раскладки
+// LayoutFlatPrefixed, которые могут делить несколько задач, удаляются только
+// файлы этой задачи и каталоги распакованных архивов.
+func (m *Manager) removeTaskFiles(t *model.Task) {
+	dir := filepath.Join(m.cfg.DownloadDir, taskDir(t))
+	if ownsDir(t) {
+		if err := os.RemoveAll(dir); err != nil {
+			slog.Error("task files delete error", "task_id", t.ID, "error", err)
+		}
+		return
+	}
+	for _, f := range t.Files {
+		paths := []string{filepath.Join(dir, diskName(t, f))}
+		if f.ExtractDir != "" {
+			paths = append(paths, filepath.Join(dir, f.ExtractDir))
+		}
+		for _, p := range paths {
+			if err := os.RemoveAll(p); err != nil {
+				slog.Error("task files delete error", "task_id", t.ID, "error", err)
+			}
+		}
+	}
+}
+
+// RemoveStaleParts удаляет из каталога загрузок временные файлы ".part",
+// оставшиеся после аварийного завершения процесса. Временные файлы
+// незавершённых (кроме отменённых) файлов известных задач не трогаются: они
+// нужны для продолжения скачивания. Чтобы не удалить чужие файлы, которые
+// лишь называются "*.part", удаляются только:
+//   - <путь файла>.part скачанных и отменённых файлов известных задач;
+//   - *.part в каталоге {id} задачи, которой принадлежит весь каталог, или
+//     задачи, которой нет в памяти (процесс упал до записи снапшота, задача
+//     вытеснена или удалена без файлов);
+//   - *.part с префиксом "{id}_" раскладки LayoutFlatPrefixed, если задачи
+//     id нет в памяти.
+//
+// Файлы задач и распакованные архивы с именем "*.part" не удаляются никогда.
+// Возвращает число удалённых файлов и ошибки обхода и удаления. Вызывать
+// после LoadFromSnapshot и до StartWorkers, пока скачивания не начались.
+func (m *Manager) RemoveStaleParts() (int, error) {
+	keep := make(map[string]bool)
+	stale := make(map[string]bool)
+	var extractDirs []string
+	owned := make(map[string]bool) // каталоги {id}, принадлежащие одной задаче
+	known := make(map[string]bool)
+	m.mu.RLock()
+	for id, t := range m.tasks {
+		known[id] = true
+		if ownsDir(t) {
+			owned[id] = true
+		}
+		for _, f := range t.Files {
+			if f.Filename == "" {
+				continue
+			}
+			path := m.filePath(t, f)
+			keep[path] = true
+			if f.ExtractDir != "" {
+				extractDirs = append(extractDirs, filepath.Join(m.cfg.DownloadDir, taskDir(t), f.ExtractDir))
+			}
+			if f.Status == model.StatusCompleted || f.Status == model.StatusCanceled {
+				stale[path+".part"] = true
+			} else {
+				keep[path+".part"] = true
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	// orphan сообщает, что path относится к каталогу или префиксу задачи,
+	// которой нет в памяти, или лежит в собственном каталоге известной задачи.
+	orphan := func(path string) bool {
+		rel, err := filepath.Rel(m.cfg.DownloadDir, path)
+		if err != nil {
+			return false
+		}
+		if top, _, ok := strings.Cut(rel, string(filepath.Separator)); ok {
+			if owned[top] || util.ValidID(top) && !known[top] {
+				return true
+			}
+		}
+		name := filepath.Base(path)
+		for i := range len(name) {
+			if name[i] == '_' && util.ValidID(name[:i]) && !known[name[:i]] {
+				return true
+			}
+		}
+		return false
+	}
+
+	removed := 0
+	var errs []error
+	err := filepath.WalkDir(m.cfg.DownloadDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			// каталога загрузок ещё нет — удалять нечего
+			if path == m.cfg.DownloadDir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			errs = append(errs, err)
+			return nil
+		}
+		if d.IsDir() {
+			if insideAny(path, extractDirs) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !strings.HasSuffix(d.Name(), ".part") || keep[path] {
+			return nil
+		}
+		if !stale[path] && !orphan(path) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// insideAny сообщает, лежит ли path внутри одного из каталогов dirs.
+func insideAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
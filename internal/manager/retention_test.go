@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"hh03012025/internal/model"
+	"hh03012025/internal/util"
+)
+
+func TestRemoveStaleParts(t *testing.T) {
+	m := newTestManager(t, 100, Config{}, nil)
+	dir := m.cfg.DownloadDir
+	id := util.GenerateID("")
+	task := &model.Task{ID: id, Status: model.StatusInProgress, Files: []model.FileState{
+		{Filename: "data", Status: model.StatusCompleted},
+		// скачанный файл, имя которого оканчивается на .part, и он же —
+		// временный файл для "data"
+		{Filename: "data.part", Status: model.StatusCompleted},
+		{Filename: "report.bin", Status: model.StatusCompleted},
+		{Filename: "canceled.bin", Status: model.StatusCanceled},
+		{Filename: "pending.bin", Status: model.StatusPending},
+		{Filename: "failed.bin", Status: model.StatusError},
+		{Filename: "archive.zip", Status: model.StatusCompleted, ExtractDir: "archive"},
+	}}
+	m.tasks[task.ID] = task
+	// задачи с общим DestSubdir: временный файл "inner" задачи t3 совпадает
+	// с файлом, извлечённым из архива задачи t2
+	m.tasks["t2"] = &model.Task{ID: "t2", DestSubdir: "shared", Status: model.StatusCompleted, Files: []model.FileState{
+		{Filename: "bundle.zip", Status: model.StatusCompleted, ExtractDir: "bundle"},
+	}}
+	m.tasks["t3"] = &model.Task{ID: "t3", DestSubdir: "shared/bundle", Status: model.StatusCompleted, Files: []model.FileState{
+		{Filename: "inner", Status: model.StatusCompleted},
+	}}
+	flatID := util.GenerateID("")
+	m.tasks[flatID] = &model.Task{ID: flatID, Layout: LayoutFlatPrefixed, Status: model.StatusInProgress, Files: []model.FileState{
+		{Filename: "pending.bin", Status: model.StatusPending},
+		{Filename: "done.bin", Status: model.StatusCompleted},
+	}}
+	// задачи, которых нет в памяти: процесс упал до записи снапшота
+	lost := util.GenerateID("")
+	lostPrefixed := util.GenerateID("job_")
+	lostUUID := util.GenerateUUID("")
+
+	tests := []struct {
+		path    string
+		removed bool
+	}{
+		{id + "/data.part", false},
+		{id + "/report.bin.part", true},
+		{id + "/canceled.bin.part", true},
+		{id + "/pending.bin.part", false},
+		{id + "/failed.bin.part", false},
+		{id + "/archive.zip.part", true},
+		{id + "/archive/inner.part", false},
+		{id + "/unknown.part", true},
+		{"shared/bundle.zip.part", true},
+		{"shared/bundle/inner.part", false},
+		{"shared/unknown.part", false},
+		{flatID + "_pending.bin.part", false},
+		{flatID + "_done.bin.part", true},
+		{flatID + "_unknown.part", false},
+		{lost + "/a.bin.part", true},
+		{lost + "/sub/b.bin.part", true},
+		{lost + "/a.bin", false},
+		{lostUUID + "/a.bin.part", true},
+		{lost + "_a.bin.part", true},
+		{lostPrefixed + "_a.bin.part", true},
+		{"out/" + lost + "_b.bin.part", true},
+		{"other/x.part", false},
+		{"x.part", false},
+		{"not_an_id_x.part", false},
+	}
+	for _, tt := range tests {
+		p := filepath.Join(dir, tt.path)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := m.RemoveStaleParts()
+	if err != nil {
+		t.Fatalf("RemoveStaleParts: %v", err)
+	}
+	want := 0
+	for _, tt := range tests {
+		_, err := os.Stat(filepath.Join(dir, tt.path))
+		if removed := os.IsNotExist(err); removed != tt.removed {
+			t.Errorf("%s: removed = %v, want %v", tt.path, removed, tt.removed)
+		}
+		if tt.removed {
+			want++
+		}
+	}
+	if n != want {
+		t.Errorf("removed count = %d, want %d", n, want)
+	}
+}
+
+func TestRemoveStalePartsMissingDir(t *testing.T) {
+	m := newTestManager(t, 100, Config{DownloadDir: filepath.Join(t.TempDir(), "missing")}, nil)
+	if n, err := m.RemoveStaleParts(); n != 0 || err != nil {
+		t.Errorf("RemoveStaleParts = %d, %v; want 0, nil", n, err)
+	}
+}
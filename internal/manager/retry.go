@@ -0,0 +1,126 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"hh03012025/internal/download"
+)
+
+// DefaultRetryBackoff — пауза перед первой автоматической повторной
+// попыткой скачивания; каждая следующая вдвое длиннее.
+const DefaultRetryBackoff = time.Second
+
+// maxRetryDelay ограничивает паузу между попытками, в том числе заданную
+// сервером в Retry-After.
+const maxRetryDelay = 5 * time.Minute
+
+// DefaultRetryableStatuses возвращает коды ответа, после которых скачивание
+// по умолчанию повторяется: 408, 429 и все 5xx.
+func DefaultRetryableStatuses() []int {
+	codes := []int{http.StatusRequestTimeout, http.StatusTooManyRequests}
+	for c := 500; c < 600; c++ {
+		codes = append(codes, c)
+	}
+	return codes
+}
+
+// retryable сообщает, что ошибка err временная и попытку стоит повторить:
+// ответ с кодом из Config.RetryableStatuses или сбой сети (нет соединения,
+// обрыв передачи, таймаут простоя). Остальные ошибки — 404, превышение
+// лимитов, несовпадение типа, запрет адреса — постоянные.
+func (m *Manager) retryable(err error) bool {
+	var statusErr *download.StatusError
+	if errors.As(err, &statusErr) {
+		return m.retryStatuses[statusErr.Code]
+	}
+	if errors.Is(err, download.ErrPrivateAddress) {
+		return false
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, download.ErrIdleTimeout) || errors.Is(err, download.ErrConnectTimeout) ||
+		errors.Is(err, download.ErrSizeMismatch)
+}
+
+// retryDelay возвращает паузу перед повторной попыткой номер attempt
+// (с 1): Retry-After ответа, если сервер его прислал, иначе
+// экспоненциальную паузу от Config.RetryBackoff.
+func (m *Manager) retryDelay(err error, attempt int) time.Duration {
+	var statusErr *download.StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return min(statusErr.RetryAfter, maxRetryDelay)
+	}
+	delay := m.cfg.RetryBackoff
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// downloadRetry скачивает reqURL, повторяя попытку после временных ошибок
+// не больше Config.DownloadRetries раз. Постоянная ошибка возвращается
+// сразу, не расходуя попыток. Паузы между попытками прерываются отменой ctx.
+// displayURL — тот же URL без учётных данных, для логов.
+func (m *Manager) downloadRetry(ctx context.Context, job Job, displayURL, reqURL, dest string, opts download.Options) (download.Result, error) {
+	for attempt := 1; ; attempt++ {
+		var res download.Result
+		m.resetProgress(job)
+		err := m.preflight(ctx, job, reqURL, dest, opts)
+		if err == nil {
+			res, err = m.downloaders.Download(ctx, reqURL, dest, opts)
+		}
+		m.recordStatusCode(job, res, err)
+		if err == nil || attempt > m.cfg.DownloadRetries || ctx.Err() != nil || !m.retryable(err) {
+			return res, err
+		}
+		delay := m.retryDelay(err, attempt)
+		slog.Warn("download attempt failed, retrying", "task_id", job.TaskID, "file_index", job.FileIndex,
+			"url", displayURL, "attempt", attempt, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return res, err
+		case <-time.After(delay):
+		}
+		m.countRetry(job)
+	}
+}
+
+// recordStatusCode сохраняет в состоянии файла код последнего ответа HTTP:
+// успешного из res или неуспешного из err. Ошибки без ответа (сбой сети) код
+// не меняют.
+func (m *Manager) recordStatusCode(job Job, res download.Result, err error) {
+	code := res.StatusCode
+	var statusErr *download.StatusError
+	if errors.As(err, &statusErr) {
+		code = statusErr.Code
+	}
+	if code == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if task, ok := m.tasks[job.TaskID]; ok && job.FileIndex >= 0 && job.FileIndex < len(task.Files) {
+		task.Files[job.FileIndex].StatusCode = code
+	}
+}
+
+// countRetry учитывает автоматическую повторную попытку в RetryCount и
+// LastAttemptAt файла.
+func (m *Manager) countRetry(job Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if task, ok := m.tasks[job.TaskID]; ok && job.FileIndex >= 0 && job.FileIndex < len(task.Files) {
+		now := time.Now().UTC()
+		f := &task.Files[job.FileIndex]
+		f.RetryCount++
+		f.LastAttemptAt = &now
+		task.UpdatedAt = now
+		m.notify(job.TaskID)
+	}
+}
@@ -0,0 +1,108 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"hh03012025/internal/model"
+)
+
+// ErrStartAtInPast возвращается AddTask, если время отложенного запуска уже
+// прошло, а Config.RunPastStartAt выключен.
+var ErrStartAtInPast = errors.New("start_at is in the past")
+
+// ScheduleLoop ставит в очередь файлы отложенных задач, когда наступает их
+// StartAt. Между запусками спит до ближайшего StartAt или до появления новой
+// отложенной задачи. Работает до отмены ctx; запускать после StartWorkers,
+// так как постановка в очередь блокируется, пока в ней нет места.
+func (m *Manager) ScheduleLoop(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		next := m.startDue(time.Now().UTC())
+		var wait <-chan time.Time
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+			wait = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.scheduleWake:
+		case <-wait:
+		}
+	}
+}
+
+// wakeScheduler будит ScheduleLoop, чтобы он учёл новую отложенную задачу.
+func (m *Manager) wakeScheduler() {
+	select {
+	case m.scheduleWake <- struct{}{}:
+	default:
+	}
+}
+
+// startDue запускает отложенные задачи, StartAt которых не позже now, и
+// возвращает ближайший StartAt оставшихся (нулевое время, если их нет).
+// Файлы приостановленной задачи в очередь не ставятся и ждут ResumeTask.
+// Задачи, наступившие одновременно (например, после перезапуска), ставятся
+// в очередь в порядке StartAt и времени создания, как при LoadFromSnapshot,
+// а не в случайном порядке обхода map.
+func (m *Manager) startDue(now time.Time) time.Time {
+	type due struct {
+		id        string
+		files     []int
+		startAt   time.Time
+		createdAt time.Time
+	}
+	var started []due
+	var next time.Time
+	m.mu.Lock()
+	for id, t := range m.tasks {
+		if t.StartAt == nil {
+			continue
+		}
+		if t.StartAt.After(now) {
+			if next.IsZero() || t.StartAt.Before(next) {
+				next = *t.StartAt
+			}
+			continue
+		}
+		d := due{id: id, startAt: *t.StartAt, createdAt: t.CreatedAt}
+		t.StartAt = nil
+		t.UpdatedAt = now
+		m.recomputeStatus(t)
+		m.notify(id)
+		for idx, f := range t.Files {
+			if f.Status == model.StatusPending && !t.Paused {
+				d.files = append(d.files, idx)
+			}
+		}
+		started = append(started, d)
+	}
+	draining := m.draining
+	m.mu.Unlock()
+	sort.Slice(started, func(i, j int) bool {
+		a, b := started[i], started[j]
+		if !a.startAt.Equal(b.startAt) {
+			return a.startAt.Before(b.startAt)
+		}
+		if !a.createdAt.Equal(b.createdAt) {
+			return a.createdAt.Before(b.createdAt)
+		}
+		return a.id < b.id
+	})
+	for _, d := range started {
+		slog.Info("scheduled task started", "task_id", d.id, "files", len(d.files))
+		if !draining {
+			for _, idx := range d.files {
+				m.enqueueJob(d.id, idx)
+			}
+		}
+		m.persistTask(d.id)
+	}
+	return next
+}
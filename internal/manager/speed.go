@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"math"
+	"time"
+
+	"hh03012025/internal/model"
+)
+
+// speedSampleInterval — как часто пересчитывается скорость скачивания файла.
+// Между замерами addProgress лишь сравнивает время, поэтому расчёт почти не
+// замедляет копирование.
+const speedSampleInterval = time.Second
+
+// speedSmoothing — вес нового замера в экспоненциальном скользящем среднем
+// скорости.
+const speedSmoothing = 0.3
+
+// speedSample — состояние расчёта скорости одного скачивания.
+type speedSample struct {
+	at    time.Time // время последнего замера
+	bytes int64     // Downloaded на момент последнего замера
+	bps   float64   // сглаженная скорость, байт в секунду
+}
+
+// startSpeed начинает расчёт скорости для новой попытки скачивания job.
+// Вызывается под m.mu.
+func (m *Manager) startSpeed(job Job, f *model.FileState, now time.Time) {
+	m.speeds[job] = &speedSample{at: now}
+	f.Speed, f.ETA = 0, 0
+}
+
+// sampleSpeed обновляет скорость и оставшееся время файла f, если с
+// прошлого замера прошло не меньше speedSampleInterval. ETA известно только
+// для файлов известного размера. Вызывается под m.mu.
+func (m *Manager) sampleSpeed(job Job, f *model.FileState, now time.Time) {
+	s := m.speeds[job]
+	if s == nil {
+		return
+	}
+	elapsed := now.Sub(s.at)
+	if elapsed < speedSampleInterval {
+		return
+	}
+	rate := float64(f.Downloaded-s.bytes) / elapsed.Seconds()
+	if s.bps == 0 {
+		s.bps = rate
+	} else {
+		s.bps = speedSmoothing*rate + (1-speedSmoothing)*s.bps
+	}
+	s.at, s.bytes = now, f.Downloaded
+	f.Speed = int64(s.bps)
+	f.ETA = 0
+	if remaining := f.Size - f.Downloaded; f.Size > 0 && remaining > 0 && s.bps > 0 {
+		f.ETA = int64(math.Ceil(float64(remaining) / s.bps))
+	}
+}
+
+// stopSpeed завершает расчёт скорости job и обнуляет её у файла f.
+// Вызывается под m.mu.
+func (m *Manager) stopSpeed(job Job, f *model.FileState) {
+	delete(m.speeds, job)
+	f.Speed, f.ETA = 0, 0
+}
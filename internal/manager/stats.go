@@ -0,0 +1,48 @@
+package manager
+
+import "hh03012025/internal/model"
+
+// Stats — сводная статистика сервиса для GET /stats.
+type Stats struct {
+	Tasks         int                  `json:"tasks"`
+	TasksByStatus map[model.Status]int `json:"tasks_by_status"`
+	Files         int                  `json:"files"`
+	FilesByStatus map[model.Status]int `json:"files_by_status"`
+	// BytesDownloaded — байты, скачанные файлами задач в памяти: размер
+	// скачанных файлов и уже записанная часть остальных.
+	BytesDownloaded int64 `json:"bytes_downloaded"`
+	// DiskUsage — суммарный размер скачанных файлов, учитываемый в лимите
+	// Config.MaxDiskUsage.
+	DiskUsage  int64 `json:"disk_usage"`
+	QueueDepth int   `json:"queue_depth"`
+	Workers    int   `json:"workers"`
+}
+
+// Stats собирает статистику по задачам в памяти за один проход под
+// блокировкой на чтение: время работы — O(число файлов всех задач).
+// Удалённые по лимиту или TTL задачи в статистику не попадают.
+func (m *Manager) Stats() Stats {
+	st := Stats{
+		TasksByStatus: make(map[model.Status]int),
+		FilesByStatus: make(map[model.Status]int),
+		QueueDepth:    m.QueueDepth(),
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	st.Workers = m.workers
+	st.DiskUsage = m.diskUsage
+	st.Tasks = len(m.tasks)
+	for _, t := range m.tasks {
+		st.TasksByStatus[t.Status]++
+		st.Files += len(t.Files)
+		for _, f := range t.Files {
+			st.FilesByStatus[f.Status]++
+			n := f.Downloaded
+			if f.Status == model.StatusCompleted {
+				n = max(n, f.Size)
+			}
+			st.BytesDownloaded += n
+		}
+	}
+	return st
+}
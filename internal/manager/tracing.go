@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"context"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"hh03012025/internal/model"
+)
+
+// tracer создаёт спаны задач и скачиваний. Берётся из глобального
+// TracerProvider (см. tracing.Setup); без него спаны — no-op.
+var tracer = otel.Tracer("hh03012025/internal/manager")
+
+// traceContext переводит контекст спана в заголовок W3C traceparent и
+// обратно, чтобы сохранять его в задаче.
+var traceContext propagation.TraceContext
+
+// startTaskSpan открывает спан задачи t, дочерний к parent (например, из
+// заголовка traceparent запроса), и запоминает его контекст в
+// t.TraceParent. Спан закрывается endTaskSpan при завершении задачи.
+// Вызывать под m.mu.
+func (m *Manager) startTaskSpan(t *model.Task, parent trace.SpanContext) {
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+	ctx, span := tracer.Start(ctx, "task", trace.WithAttributes(
+		attribute.String("downloader.task_id", t.ID),
+		attribute.Int("downloader.files", len(t.Files)),
+		attribute.String("downloader.priority", t.Priority),
+	))
+	m.spans[t.ID] = span
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+	t.TraceParent = carrier.Get("traceparent")
+}
+
+// endTaskSpan закрывает спан завершённой задачи t с её итоговым статусом.
+// Спан задачи, созданной до перезапуска сервиса, не восстанавливается, и
+// закрывать нечего. Вызывать под m.mu.
+func (m *Manager) endTaskSpan(t *model.Task) {
+	span, ok := m.spans[t.ID]
+	if !ok {
+		return
+	}
+	delete(m.spans, t.ID)
+	completed := 0
+	for _, f := range t.Files {
+		if f.Status == model.StatusCompleted {
+			completed++
+		}
+	}
+	span.SetAttributes(
+		attribute.String("downloader.status", string(t.Status)),
+		attribute.Int("downloader.files_completed", completed),
+	)
+	if t.Status == model.StatusCompletedWithErrors {
+		span.SetStatus(codes.Error, "some files failed")
+	}
+	span.End()
+}
+
+// startFileSpan открывает спан скачивания файла index задачи t, дочерний к
+// спану задачи. Контекст родителя берётся из t.TraceParent, поэтому связь
+// сохраняется и после перезапуска сервиса. Вызывать под m.mu.
+func startFileSpan(t *model.Task, index int) trace.Span {
+	ctx := traceContext.Extract(context.Background(), propagation.MapCarrier{"traceparent": t.TraceParent})
+	f := t.Files[index]
+	attrs := []attribute.KeyValue{
+		attribute.String("downloader.task_id", t.ID),
+		attribute.Int("downloader.file_index", index),
+		attribute.Int("downloader.retry_count", f.RetryCount),
+	}
+	if u, err := url.Parse(f.URL); err == nil {
+		attrs = append(attrs, attribute.String("url.scheme", u.Scheme), attribute.String("server.address", u.Hostname()))
+	}
+	_, span := tracer.Start(ctx, "download file", trace.WithAttributes(attrs...))
+	return span
+}
+
+// endFileSpan закрывает спан скачивания с итоговым состоянием файла job:
+// статусом, числом байт и ошибкой.
+func (m *Manager) endFileSpan(span trace.Span, job Job) {
+	m.mu.RLock()
+	var f model.FileState
+	if t, ok := m.tasks[job.TaskID]; ok && job.FileIndex < len(t.Files) {
+		f = t.Files[job.FileIndex]
+	}
+	m.mu.RUnlock()
+	span.SetAttributes(
+		attribute.String("downloader.status", string(f.Status)),
+		attribute.Int64("downloader.bytes", max(f.Size, f.Downloaded)),
+	)
+	if f.SourceURL != "" {
+		if u, err := url.Parse(f.SourceURL); err == nil {
+			span.SetAttributes(attribute.String("downloader.mirror_host", u.Hostname()))
+		}
+	}
+	if f.Status == model.StatusError {
+		span.SetStatus(codes.Error, f.Error)
+	}
+	span.End()
+}
@@ -0,0 +1,120 @@
+package manager
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"hh03012025/internal/model"
+)
+
+// ErrWorkersNotStarted возвращается SetWorkers, если пул воркеров ещё не
+// запущен StartWorkers.
+var ErrWorkersNotStarted = errors.New("workers not started")
+
+// Workers возвращает текущее число воркеров.
+func (m *Manager) Workers() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.workers
+}
+
+// SetWorkers изменяет число воркеров до n и возвращает его. Новые воркеры
+// запускаются сразу. При уменьшении лишние воркеры завершаются: свободные
+// сразу, занятые — после текущего задания, поэтому задания в очереди не
+// теряются и скачивания не прерываются. Воркеры, которые ещё не успели
+// завершиться после уменьшения, продолжают работу, если пул снова
+// увеличен, так что число воркеров всегда сходится к последнему n.
+func (m *Manager) SetWorkers(n int) (int, error) {
+	if n <= 0 {
+		return 0, errors.New("workers must be positive")
+	}
+	m.mu.Lock()
+	ctx := m.workerCtx
+	if ctx == nil {
+		m.mu.Unlock()
+		return 0, ErrWorkersNotStarted
+	}
+	prev := m.workers
+	m.workers = n
+	start := max(n-m.running, 0)
+	m.running += start
+	if m.running > n {
+		close(m.shrink)
+		m.shrink = make(chan struct{})
+	}
+	m.mu.Unlock()
+
+	for range start {
+		m.wg.Add(1)
+		go m.runWorker(ctx)
+	}
+	slog.Info("workers resized", "from", prev, "to", n)
+	return n, nil
+}
+
+// ActiveJob — задание, которое воркер обрабатывает прямо сейчас.
+type ActiveJob struct {
+	TaskID    string       `json:"task_id"`
+	FileIndex int          `json:"file_index"`
+	URL       string       `json:"url"`
+	Status    model.Status `json:"status"`
+	StartedAt time.Time    `json:"started_at"`
+	// Elapsed — время обработки задания в секундах.
+	Elapsed float64 `json:"elapsed_seconds"`
+}
+
+// WorkersInfo описывает состояние пула воркеров и очереди для отладки
+// зависаний.
+type WorkersInfo struct {
+	Workers       int            `json:"workers"`
+	QueueLength   int            `json:"queue_length"`
+	QueueCapacity int            `json:"queue_capacity"`
+	QueueByPrio   map[string]int `json:"queue_by_priority"`
+	// Active — обрабатываемые задания, от самых долгих к самым новым.
+	Active []ActiveJob `json:"active"`
+}
+
+// WorkersInfo возвращает число воркеров, заполненность очереди по
+// приоритетам и задания, которые воркеры обрабатывают сейчас.
+func (m *Manager) WorkersInfo() WorkersInfo {
+	info := WorkersInfo{
+		QueueLength:   m.queue.len(),
+		QueueCapacity: m.queue.cap(),
+		QueueByPrio: map[string]int{
+			PriorityHigh:   len(m.queue.high),
+			PriorityNormal: len(m.queue.normal),
+			PriorityLow:    len(m.queue.low),
+		},
+		Active: []ActiveJob{},
+	}
+	now := time.Now().UTC()
+	m.mu.RLock()
+	info.Workers = m.workers
+	for job, started := range m.active {
+		a := ActiveJob{
+			TaskID:    job.TaskID,
+			FileIndex: job.FileIndex,
+			StartedAt: started,
+			Elapsed:   now.Sub(started).Seconds(),
+		}
+		if t, ok := m.tasks[job.TaskID]; ok && job.FileIndex < len(t.Files) {
+			a.URL = t.Files[job.FileIndex].URL
+			a.Status = t.Files[job.FileIndex].Status
+		}
+		info.Active = append(info.Active, a)
+	}
+	m.mu.RUnlock()
+	sort.Slice(info.Active, func(i, j int) bool {
+		return info.Active[i].StartedAt.Before(info.Active[j].StartedAt)
+	})
+	return info
+}
+
+// finishActive снимает задание job с учёта обрабатываемых.
+func (m *Manager) finishActive(job Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.active, job)
+}
@@ -0,0 +1,128 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"hh03012025/internal/model"
+)
+
+// blockingSource — источник, который держит каждый запрос до release и
+// считает одновременные запросы.
+type blockingSource struct {
+	release chan struct{}
+
+	mu        sync.Mutex
+	inflight  int
+	maxFlight int
+}
+
+func (s *blockingSource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.inflight++
+	s.maxFlight = max(s.maxFlight, s.inflight)
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inflight--
+		s.mu.Unlock()
+	}()
+	select {
+	case <-s.release:
+		_, _ = w.Write([]byte("content"))
+	case <-r.Context().Done():
+	}
+}
+
+// waitInflight ждёт, пока одновременно выполняются ровно n запросов.
+func (s *blockingSource) waitInflight(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		got := s.inflight
+		s.mu.Unlock()
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests in flight, want %d", got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSetWorkersShrinkThenGrow(t *testing.T) {
+	const files = 12
+	src := &blockingSource{release: make(chan struct{})}
+	srv := httptest.NewServer(src)
+	defer srv.Close()
+
+	m := newTestManager(t, 100, Config{AllowPrivateIPs: true}, nil)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer func() {
+		cancel()
+		m.Wait(context.Background())
+	}()
+	m.StartWorkers(ctx, 4)
+	var specs []FileSpec
+	for i := range files {
+		specs = append(specs, FileSpec{URL: fmt.Sprintf("%s/%d", srv.URL, i)})
+	}
+	task, _, err := m.AddTask(TaskSpec{Files: specs})
+	if err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+	src.waitInflight(t, 4)
+
+	// все воркеры заняты: уменьшение и сразу же увеличение пула не должно ни
+	// потерять воркеров, ни запустить лишних
+	for _, n := range []int{1, 4} {
+		if _, err := m.SetWorkers(n); err != nil {
+			t.Fatalf("SetWorkers(%d): %v", n, err)
+		}
+	}
+	for range 4 {
+		src.release <- struct{}{}
+	}
+	src.waitInflight(t, 4)
+	src.mu.Lock()
+	maxFlight := src.maxFlight
+	src.mu.Unlock()
+	if maxFlight > 4 {
+		t.Errorf("%d downloads ran at once with 4 workers", maxFlight)
+	}
+
+	// уменьшение без последующего роста оставляет одного воркера
+	if _, err := m.SetWorkers(1); err != nil {
+		t.Fatalf("SetWorkers(1): %v", err)
+	}
+	for range 4 {
+		src.release <- struct{}{}
+	}
+	src.waitInflight(t, 1)
+
+	close(src.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, _ := m.GetTask(task.ID)
+		if got.Status == model.StatusCompleted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task status %q, want completed", got.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.mu.RLock()
+	running, workers := m.running, m.workers
+	m.mu.RUnlock()
+	if running != 1 || workers != 1 {
+		t.Errorf("running %d, workers %d; want 1, 1", running, workers)
+	}
+}
@@ -0,0 +1,64 @@
+// Package metrics содержит Prometheus‑метрики сервиса загрузки файлов.
+//
+// Экспортируемые метрики:
+//   - downloader_tasks_created_total      — число созданных задач;
+//   - downloader_files_downloaded_total   — число успешно скачанных файлов;
+//   - downloader_files_failed_total       — число файлов, завершившихся ошибкой;
+//   - downloader_bytes_downloaded_total   — число скачанных байт;
+//   - downloader_queue_depth              — текущая длина очереди заданий;
+//   - downloader_tasks                    — число задач в памяти;
+//   - downloader_active_workers           — число воркеров, занятых скачиванием.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// TasksCreated увеличивается при создании каждой задачи.
+	TasksCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "downloader_tasks_created_total",
+		Help: "Total number of download tasks created.",
+	})
+	// FilesDownloaded увеличивается, когда файл успешно скачан.
+	FilesDownloaded = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "downloader_files_downloaded_total",
+		Help: "Total number of files downloaded successfully.",
+	})
+	// FilesFailed увеличивается, когда скачивание файла завершилось ошибкой.
+	FilesFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "downloader_files_failed_total",
+		Help: "Total number of files whose download failed.",
+	})
+	// BytesDownloaded учитывает все байты, записанные на диск.
+	BytesDownloaded = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "downloader_bytes_downloaded_total",
+		Help: "Total number of bytes written to disk by downloads.",
+	})
+	// ActiveWorkers показывает, сколько воркеров сейчас скачивают файлы.
+	ActiveWorkers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "downloader_active_workers",
+		Help: "Number of workers currently downloading a file.",
+	})
+)
+
+// Register регистрирует все метрики в reg. Функции queueDepth и taskCount
+// вызываются при каждом сборе метрик и должны возвращать текущую длину
+// очереди заданий и число задач в памяти.
+func Register(reg prometheus.Registerer, queueDepth, taskCount func() int) error {
+	queue := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "downloader_queue_depth",
+		Help: "Number of jobs waiting in the download queue.",
+	}, func() float64 { return float64(queueDepth()) })
+	tasks := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "downloader_tasks",
+		Help: "Number of tasks retained in memory.",
+	}, func() float64 { return float64(taskCount()) })
+	collectors := []prometheus.Collector{
+		TasksCreated, FilesDownloaded, FilesFailed, BytesDownloaded, ActiveWorkers, queue, tasks,
+	}
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
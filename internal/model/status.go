@@ -0,0 +1,44 @@
+package model
+
+// Status — статус файла или задачи. Файлы бывают в статусах StatusPending,
+// StatusInProgress, StatusCompleted, StatusError и StatusCanceled; задачи —
+// в StatusPending, StatusScheduled, StatusInProgress, StatusCompleted,
+// StatusCompletedWithErrors, StatusCanceled и StatusPaused.
+type Status string
+
+const (
+	StatusPending             Status = "pending"               // ожидает скачивания
+	StatusScheduled           Status = "scheduled"             // задача ждёт времени отложенного запуска
+	StatusInProgress          Status = "in-progress"           // скачивается
+	StatusCompleted           Status = "completed"             // скачано
+	StatusError               Status = "error"                 // скачивание файла завершилось ошибкой
+	StatusCompletedWithErrors Status = "completed_with_errors" // задача завершена, но часть файлов с ошибкой
+	StatusCanceled            Status = "canceled"              // отменено пользователем
+	StatusPaused              Status = "paused"                // задача приостановлена
+)
+
+// String возвращает строковое значение статуса.
+func (s Status) String() string { return string(s) }
+
+// Valid сообщает, является ли s одним из известных статусов.
+func (s Status) Valid() bool {
+	switch s {
+	case StatusPending, StatusScheduled, StatusInProgress, StatusCompleted, StatusError,
+		StatusCompletedWithErrors, StatusCanceled, StatusPaused:
+		return true
+	}
+	return false
+}
+
+// legacyStatusInProgress — значение StatusInProgress в старых снапшотах: в
+// нём вместо дефиса стоял неразрывный дефис U+2011, из-за чего сравнение с
+// "in-progress" на стороне клиентов не срабатывало.
+const legacyStatusInProgress Status = "in\u2011progress"
+
+// NormalizeStatus заменяет устаревшее значение статуса актуальным.
+func NormalizeStatus(status Status) Status {
+	if status == legacyStatusInProgress {
+		return StatusInProgress
+	}
+	return status
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStatusValid(t *testing.T) {
+	tests := []struct {
+		status Status
+		want   string
+		valid  bool
+	}{
+		{StatusPending, "pending", true},
+		{StatusScheduled, "scheduled", true},
+		{StatusInProgress, "in-progress", true},
+		{StatusCompleted, "completed", true},
+		{StatusError, "error", true},
+		{StatusCompletedWithErrors, "completed_with_errors", true},
+		{StatusCanceled, "canceled", true},
+		{StatusPaused, "paused", true},
+		{"", "", false},
+		{"in\u2011progress", "in\u2011progress", false},
+		{"Completed", "Completed", false},
+	}
+	for _, tt := range tests {
+		if got := tt.status.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+		if got := tt.status.Valid(); got != tt.valid {
+			t.Errorf("%q.Valid() = %v, want %v", tt.status, got, tt.valid)
+		}
+	}
+}
+
+func TestNormalizeStatus(t *testing.T) {
+	tests := []struct {
+		in, want Status
+	}{
+		{"in\u2011progress", StatusInProgress},
+		{StatusInProgress, StatusInProgress},
+		{StatusPending, StatusPending},
+		{StatusCompleted, StatusCompleted},
+		{"unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeStatus(tt.in); got != tt.want {
+			t.Errorf("NormalizeStatus(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestStringLiteralsAreASCIIHyphenated проверяет, что в строковых литералах
+// исходников нет неразрывного дефиса U+2011: из-за него статус
+// "in\u2011progress" когда-то не совпадал с "in-progress" у клиентов. В
+// комментариях он допустим, а в литералах — только экранированным (\u2011).
+func TestStringLiteralsAreASCIIHyphenated(t *testing.T) {
+	root := filepath.Join("..", "..")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.BasicLit); ok && strings.ContainsRune(lit.Value, '\u2011') {
+				t.Errorf("%s: string literal %s contains U+2011", fset.Position(lit.Pos()), lit.Value)
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
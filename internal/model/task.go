@@ -4,25 +4,110 @@ import "time"
 
 // FileState описывает состояние отдельного файла в задаче.
 // Файл может находиться в одном из состояний: "pending" (ожидание),
-// "in‑progress" (скачивание в процессе), "completed" (скачан) или "error" (ошибка).
-// Поле Error заполняется, если при скачивании произошла ошибка.
+// "in-progress" (скачивание в процессе), "completed" (скачан), "error" (ошибка)
+// или "canceled" (скачивание отменено пользователем).
+// Поле Error заполняется, если при скачивании произошла ошибка. Filename —
+// имя файла в каталоге задачи; выбирается при создании задачи с учётом
+// коллизий и не меняется между перезапусками. Path — путь к файлу
+// относительно каталога загрузок; заполняется, когда начинается скачивание.
+// LastAttemptAt — время начала последней попытки скачивания, RetryCount —
+// число автоматических повторов после временных ошибок (см.
+// -download-retries); возобновление после паузы или перезапуска
+// сервиса и ручной повтор его не увеличивают.
+// Mirrors — запасные URL того же содержимого: при ошибке скачивания с URL
+// они пробуются по порядку, а SourceURL запоминает сработавшее зеркало.
+// Size и ContentType известны после HEAD-запроса (если он включён) или
+// получения ответа на GET; Downloaded — число байт, записанных текущей
+// попыткой. Speed и ETA — сглаженная скорость и оценка оставшегося времени
+// скачивания; пересчитываются раз в секунду и обнуляются по его окончании.
+// ExpectedContentType — ожидаемый тип содержимого: ответ другого типа
+// (фактический сохраняется в ContentType) завершается ошибкой. Accept
+// отправляется в одноимённом заголовке, чтобы выбрать представление
+// ресурса; выбранный сервером тип сохраняется в ContentType. ETag и
+// LastModified запоминаются из ответа, чтобы при повторном скачивании уже
+// сохранённого файла отправить условный запрос и не скачивать его при 304.
+// SHA256 — ожидаемая контрольная сумма: файл с другой суммой удаляется, а
+// скачивание завершается ошибкой. StatusCode — код последнего ответа HTTP
+// (в том числе неуспешного), для диагностики.
+// ExtractStatus и ExtractDir заполняются, если задача создана с
+// распаковкой архивов и файл распознан как архив.
+// Headers — дополнительные заголовки запроса (например, Authorization); они
+// могут содержать секреты, поэтому не сериализуются ни в снапшот, ни в
+// ответы API и не переживают перезапуск сервиса. По той же причине в URL и
+// Mirrors учётные данные (user:pass@) и чувствительные параметры запроса
+// заменены на "***", а исходные URL лежат в несериализуемом AuthURLs.
+// Secrets отмечает файлы с такими заголовками или URL: после перезапуска их
+// незавершённое скачивание не продолжается без секретов, а завершается
+// ошибкой.
 type FileState struct {
-	URL    string `json:"url"`             // original URL to download
-	Status string `json:"status"`          // one of: pending, in‑progress, completed, error
-	Error  string `json:"error,omitempty"` // description of any failure
+	URL      string            `json:"url"`                   // original URL to download
+	Status   Status            `json:"status"`                // one of: pending, in-progress, completed, error, canceled
+	Error    string            `json:"error,omitempty"`       // description of any failure
+	Filename string            `json:"filename,omitempty"`    // name of the saved file inside the task directory
+	Path     string            `json:"path,omitempty"`        // saved file path relative to the download directory
+	FinalURL string            `json:"final_url,omitempty"`   // URL after redirects, if it differs from the source
+	Headers  map[string]string `json:"-"`                     // extra request headers, never persisted
+	Secrets  bool              `json:"has_secrets,omitempty"` // request headers or URL secrets were set; they are lost on restart
+
+	Mirrors   []string `json:"mirrors,omitempty"`    // fallback URLs tried in order if URL fails
+	SourceURL string   `json:"source_url,omitempty"` // mirror the file was downloaded from, if not URL
+
+	Size        int64  `json:"size,omitempty"`             // size in bytes, from HEAD or the completed download
+	ContentType string `json:"content_type,omitempty"`     // Content-Type reported by the server
+	Downloaded  int64  `json:"downloaded_bytes,omitempty"` // bytes written by the current attempt
+	Speed       int64  `json:"speed_bps,omitempty"`        // smoothed download speed in bytes per second
+	ETA         int64  `json:"eta_seconds,omitempty"`      // estimated seconds left, only when Size is known
+
+	Accept              string `json:"accept,omitempty"`                // Accept header sent to negotiate the representation
+	ExpectedContentType string `json:"expected_content_type,omitempty"` // required Content-Type or prefix like "image/"
+	SHA256              string `json:"sha256,omitempty"`                // expected hex SHA-256 of the content, checked after download
+
+	ETag         string `json:"etag,omitempty"`          // ETag of the saved file, sent as If-None-Match on re-download
+	LastModified string `json:"last_modified,omitempty"` // Last-Modified of the saved file, sent as If-Modified-Since
+
+	PartValidator string `json:"part_validator,omitempty"` // ETag or Last-Modified of the response in the .part file, sent as If-Range on resume
+
+	StatusCode    int        `json:"status_code,omitempty"`     // HTTP status code of the most recent response
+	RetryCount    int        `json:"retry_count"`               // automatic retries after transient errors
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"` // start of the most recent attempt
+
+	ExtractStatus string `json:"extract_status,omitempty"` // one of: extracting, extracted, failed
+	ExtractDir    string `json:"extract_dir,omitempty"`    // directory with extracted contents inside the task directory
+
+	AuthURLs map[string]string `json:"-"` // URL and mirrors with secrets keyed by redacted URL, never persisted
 }
 
 // Task represents a download task submitted by the user.
 // A task contains multiple files and overall status information.
-// Status can be: "pending", "in‑progress", "completed", "completed_with_errors".
+// Status can be: "pending", "in-progress", "completed", "completed_with_errors".
 // Task описывает задачу скачивания. Содержит список файлов (Files), общий статус
 // (Status) и временные метки создания и последнего обновления. Возможные
-// значения Status: "pending" (ожидает), "in‑progress" (в процессе),
-// "completed" (все файлы скачаны), "completed_with_errors" (скачано, но были ошибки).
+// значения Status: "pending" (ожидает), "in-progress" (в процессе),
+// "completed" (все файлы скачаны), "completed_with_errors" (скачано, но были ошибки),
+// "canceled" (задача отменена пользователем), "paused" (приостановлена),
+// "scheduled" (ждёт отложенного запуска в StartAt).
 type Task struct {
-	ID        string      `json:"id"`         // уникальный идентификатор
-	Files     []FileState `json:"files"`      // список файлов и их состояния
-	Status    string      `json:"status"`     // общий статус задачи
-	CreatedAt time.Time   `json:"created_at"` // время создания
-	UpdatedAt time.Time   `json:"updated_at"` // время последнего обновления
+	ID              string      `json:"id"`                          // уникальный идентификатор
+	Files           []FileState `json:"files"`                       // список файлов и их состояния
+	Status          Status      `json:"status"`                      // общий статус задачи
+	CreatedAt       time.Time   `json:"created_at"`                  // время создания
+	UpdatedAt       time.Time   `json:"updated_at"`                  // время последнего обновления
+	IdempotencyKey  string      `json:"idempotency_key,omitempty"`   // ключ идемпотентности запроса на создание
+	CallbackURL     string      `json:"callback_url,omitempty"`      // URL для уведомления о завершении
+	FileCallbackURL string      `json:"file_callback_url,omitempty"` // URL для уведомлений о завершении каждого файла
+	Paused          bool        `json:"paused,omitempty"`            // задача приостановлена пользователем
+	Priority        string      `json:"priority,omitempty"`          // приоритет: high, normal или low
+	Extract         bool        `json:"extract,omitempty"`           // распаковывать скачанные архивы
+	MaxConcurrent   int         `json:"max_concurrent,omitempty"`    // лимит параллельных скачиваний задачи
+	DestSubdir      string      `json:"dest_subdir,omitempty"`       // каталог файлов внутри каталога загрузок вместо ID
+	Layout          string      `json:"layout,omitempty"`            // раскладка файлов: пусто (per-task) или flat-prefixed
+	Labels          Labels      `json:"labels,omitempty"`            // произвольные метки для фильтрации
+	StartAt         *time.Time  `json:"start_at,omitempty"`          // время отложенного запуска, пока он не наступил
+	Deadline        *time.Time  `json:"deadline,omitempty"`          // срок выполнения, после него незавершённые файлы — error
+	URLSetHash      string      `json:"url_set_hash,omitempty"`      // хеш набора URL для поиска дубликатов
+	TraceParent     string      `json:"trace_parent,omitempty"`      // контекст трассировки задачи (W3C traceparent)
 }
+
+// Labels — произвольные метки задачи «ключ — значение», например проект или
+// автор запроса.
+type Labels map[string]string
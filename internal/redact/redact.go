@@ -0,0 +1,89 @@
+// Package redact скрывает секреты в URL перед тем, как они попадут в логи,
+// ответы API или снапшот: учётные данные (user:pass@) и значения
+// чувствительных параметров запроса, например подписи presigned-ссылок.
+package redact
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// Mask заменяет скрытые значения.
+const Mask = "***"
+
+// DefaultQueryKeys — параметры запроса, значения которых скрываются по
+// умолчанию. Сравнение без учёта регистра.
+var DefaultQueryKeys = []string{
+	"token", "access_token", "refresh_token", "id_token", "auth", "password", "secret",
+	"api_key", "apikey", "sig", "signature",
+	"x-amz-signature", "x-amz-credential", "x-amz-security-token",
+	"x-goog-signature", "x-goog-credential",
+}
+
+// Redactor скрывает секреты в URL. Нулевое значение скрывает только
+// учётные данные; безопасен для параллельного использования.
+type Redactor struct {
+	keys map[string]struct{}
+}
+
+// New создаёт Redactor, скрывающий значения параметров запроса keys (без
+// учёта регистра) и учётные данные в URL.
+func New(keys []string) *Redactor {
+	r := &Redactor{keys: make(map[string]struct{}, len(keys))}
+	for _, k := range keys {
+		if k = strings.TrimSpace(k); k != "" {
+			r.keys[strings.ToLower(k)] = struct{}{}
+		}
+	}
+	return r
+}
+
+// URL возвращает rawURL, в котором учётные данные и значения
+// чувствительных параметров запроса заменены на Mask. Остальные параметры
+// и их порядок не меняются. URL, который не удаётся разобрать, возвращается
+// как есть.
+func (r *Redactor) URL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	hasUser := u.User != nil
+	changed := hasUser
+	u.User = nil
+	if u.RawQuery != "" && len(r.keys) > 0 {
+		params := strings.Split(u.RawQuery, "&")
+		for i, p := range params {
+			k, _, _ := strings.Cut(p, "=")
+			if name, err := url.QueryUnescape(k); err == nil {
+				k = name
+			}
+			if _, ok := r.keys[strings.ToLower(k)]; ok {
+				params[i], _, _ = strings.Cut(p, "=")
+				params[i] += "=" + Mask
+				changed = true
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+	if !changed {
+		return rawURL
+	}
+	out := u.String()
+	if hasUser {
+		// url.User экранировал бы "*", поэтому маска вставляется в строку
+		out = strings.Replace(out, "//", "//"+Mask+"@", 1)
+	}
+	return out
+}
+
+// Error скрывает секреты в URL ошибки *url.Error, которую возвращает
+// http.Client: иначе URL запроса целиком попадает в текст ошибки. Прочие
+// ошибки возвращаются без изменений.
+func (r *Redactor) Error(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		ue.URL = r.URL(ue.URL)
+	}
+	return err
+}
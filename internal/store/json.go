@@ -0,0 +1,150 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"hh03012025/internal/model"
+)
+
+// gzipMagic — первые байты файла в формате gzip.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// JSONStore хранит все задачи в одном JSON‑файле. Запись выполняется через
+// временный файл и атомарное переименование, чтобы избежать повреждения
+// данных при сбое. Файл содержит конверт с номером версии формата (см.
+// SnapshotVersion); снапшоты старых версий мигрируются при загрузке.
+type JSONStore struct {
+	path string
+	// alt — путь к снапшоту в другом формате (сжатом или нет); читается,
+	// если основного файла нет, например после включения сжатия.
+	alt      string
+	compress bool
+	// readOnly устанавливается, если файл записан более новой версией
+	// сервиса: перезаписывать его нельзя, чтобы не потерять данные.
+	readOnly bool
+	// skipped — число записей, пропущенных последним Load.
+	skipped int
+}
+
+// NewJSONStore создаёт хранилище, использующее файл path. При compress
+// снапшот сжимается gzip и записывается в path с расширением ".gz".
+func NewJSONStore(path string, compress bool) *JSONStore {
+	plain := strings.TrimSuffix(path, ".gz")
+	s := &JSONStore{path: plain, alt: plain + ".gz", compress: compress}
+	if compress {
+		s.path, s.alt = s.alt, s.path
+	}
+	return s
+}
+
+// Path возвращает путь к файлу снапшота.
+func (s *JSONStore) Path() string {
+	return s.path
+}
+
+// Load читает задачи из файла. Если файла нет, возвращает пустую карту.
+// Повторяющийся ID задачи не заменяет первую запись, а пропускается (см.
+// Skipped).
+// Сжатый gzip снапшот распознаётся по сигнатуре независимо от настройки
+// сжатия. Снапшот неизвестной (более новой) версии отклоняется с
+// ErrUnsupportedVersion, и последующие Save не перезаписывают файл.
+func (s *JSONStore) Load() (map[string]*model.Task, error) {
+	path := s.path
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		path = s.alt
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]*model.Task{}, nil
+		}
+		return nil, err
+	}
+	if bytes.HasPrefix(data, gzipMagic) {
+		if data, err = gunzip(data); err != nil {
+			return nil, fmt.Errorf("load snapshot %s: %w", path, err)
+		}
+	}
+	tasks, dups, err := decodeSnapshot(data)
+	if errors.Is(err, ErrUnsupportedVersion) {
+		s.readOnly = true
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load snapshot %s: %w", path, err)
+	}
+	for _, id := range dups {
+		slog.Warn("duplicate task ID in snapshot, keeping the first entry", "path", path, "task_id", id)
+	}
+	s.skipped = len(dups)
+	return tasks, nil
+}
+
+// Skipped возвращает число повторяющихся записей, пропущенных последним
+// Load.
+func (s *JSONStore) Skipped() int {
+	return s.skipped
+}
+
+// Save сериализует задачи в JSON и атомарно заменяет ими файл снапшота.
+func (s *JSONStore) Save(tasks map[string]*model.Task) error {
+	if s.readOnly {
+		return fmt.Errorf("refusing to overwrite %s: %w", s.path, ErrUnsupportedVersion)
+	}
+	data, err := encodeSnapshot(tasks)
+	if err != nil {
+		return err
+	}
+	if s.compress {
+		if data, err = gzipBytes(data); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	// Снапшот в другом формате устарел; удаляем, чтобы он не был прочитан
+	// вместо актуального после смены настройки.
+	if err := os.Remove(s.alt); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// gzipBytes сжимает data в формат gzip.
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gunzip распаковывает данные в формате gzip.
+func gunzip(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
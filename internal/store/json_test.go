@@ -0,0 +1,36 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONStoreSkipsDuplicateIDs(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantTasks   int
+		wantSkipped int
+	}{
+		{"no duplicates", `{"version":2,"tasks":{"a":{"id":"a"},"b":{"id":"b"}}}`, 2, 0},
+		{"duplicate", `{"version":2,"tasks":{"a":{"id":"a"},"a":{"id":"a"},"b":{"id":"b"}}}`, 2, 1},
+		{"version 1 duplicate", `{"a":{"id":"a"},"a":{"id":"a"}}`, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			s := NewJSONStore(path, false)
+			tasks, err := s.Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if len(tasks) != tt.wantTasks || s.Skipped() != tt.wantSkipped {
+				t.Errorf("loaded %d tasks, skipped %d; want %d, %d", len(tasks), s.Skipped(), tt.wantTasks, tt.wantSkipped)
+			}
+		})
+	}
+}
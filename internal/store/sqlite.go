@@ -0,0 +1,207 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"hh03012025/internal/model"
+
+	_ "modernc.org/sqlite" // драйвер database/sql "sqlite"
+)
+
+// schema описывает таблицы хранилища. Каждый файл задачи хранится отдельной
+// строкой, чтобы изменение статуса одного файла не требовало перезаписи всей
+// задачи. Колонки status/url/error дублируют поля из data для удобства
+// запросов; при загрузке используется data, поэтому новые поля моделей
+// сохраняются без изменения схемы.
+const schema = `
+CREATE TABLE IF NOT EXISTS tasks (
+	id         TEXT PRIMARY KEY,
+	status     TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	data       TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS files (
+	task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+	idx     INTEGER NOT NULL,
+	url     TEXT NOT NULL,
+	status  TEXT NOT NULL,
+	error   TEXT NOT NULL DEFAULT '',
+	data    TEXT NOT NULL,
+	PRIMARY KEY (task_id, idx)
+);
+`
+
+// sqliteSchemaVersion — версия схемы, записываемая в PRAGMA user_version.
+// База более новой версии не открывается, чтобы не испортить её данные.
+const sqliteSchemaVersion = 1
+
+// SQLiteStore хранит задачи в базе SQLite. Каждое изменение задачи
+// записывается отдельной транзакцией (см. SaveTask), поэтому при сбое
+// теряется не более одного последнего обновления.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite открывает (или создаёт) базу по пути path и применяет схему.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite допускает одного писателя; одно соединение исключает SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("read sqlite schema version: %w", err)
+	}
+	if version > sqliteSchemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("%w: sqlite schema %d (supported up to %d)", ErrUnsupportedVersion, version, sqliteSchemaVersion)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("apply sqlite schema: %w", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, sqliteSchemaVersion)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("write sqlite schema version: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Close закрывает соединение с базой.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Load читает все задачи и их файлы из базы.
+func (s *SQLiteStore) Load() (map[string]*model.Task, error) {
+	tasks := map[string]*model.Task{}
+	rows, err := s.db.Query(`SELECT id, data FROM tasks`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		var t model.Task
+		if err := json.Unmarshal([]byte(data), &t); err != nil {
+			return nil, fmt.Errorf("decode task %s: %w", id, err)
+		}
+		t.Files = nil
+		tasks[id] = &t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	frows, err := s.db.Query(`SELECT task_id, idx, data FROM files ORDER BY task_id, idx`)
+	if err != nil {
+		return nil, err
+	}
+	defer frows.Close()
+	for frows.Next() {
+		var taskID, data string
+		var idx int
+		if err := frows.Scan(&taskID, &idx, &data); err != nil {
+			return nil, err
+		}
+		t, ok := tasks[taskID]
+		if !ok {
+			continue
+		}
+		var f model.FileState
+		if err := json.Unmarshal([]byte(data), &f); err != nil {
+			return nil, fmt.Errorf("decode file %s/%d: %w", taskID, idx, err)
+		}
+		t.Files = append(t.Files, f)
+	}
+	return tasks, frows.Err()
+}
+
+// Save записывает полное состояние в одной транзакции: задачи из tasks
+// обновляются, отсутствующие в tasks — удаляются.
+func (s *SQLiteStore) Save(tasks map[string]*model.Task) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`CREATE TEMP TABLE IF NOT EXISTS keep (id TEXT PRIMARY KEY)`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM keep`); err != nil {
+		return err
+	}
+	for id, t := range tasks {
+		if err := saveTask(tx, t); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO keep (id) VALUES (?)`, id); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM tasks WHERE id NOT IN (SELECT id FROM keep)`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveTask транзакционно записывает одну задачу вместе с её файлами.
+func (s *SQLiteStore) SaveTask(t *model.Task) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := saveTask(tx, t); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteTask удаляет задачу; строки файлов удаляются каскадно.
+func (s *SQLiteStore) DeleteTask(id string) error {
+	_, err := s.db.Exec(`DELETE FROM tasks WHERE id = ?`, id)
+	return err
+}
+
+// saveTask выполняет upsert задачи и всех её файлов в рамках транзакции tx.
+func saveTask(tx *sql.Tx, t *model.Task) error {
+	head := *t
+	head.Files = nil
+	data, err := json.Marshal(head)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO tasks (id, status, created_at, updated_at, data)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET status = excluded.status,
+			updated_at = excluded.updated_at, data = excluded.data`,
+		t.ID, t.Status, t.CreatedAt, t.UpdatedAt, string(data))
+	if err != nil {
+		return err
+	}
+	for i, f := range t.Files {
+		fdata, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO files (task_id, idx, url, status, error, data)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(task_id, idx) DO UPDATE SET url = excluded.url,
+				status = excluded.status, error = excluded.error, data = excluded.data`,
+			t.ID, i, f.URL, f.Status, f.Error, string(fdata))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
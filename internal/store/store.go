@@ -0,0 +1,32 @@
+// Package store содержит реализации хранилища состояния задач: JSON‑снапшот
+// и SQLite. Менеджер работает с ними через интерфейс Store.
+package store
+
+import "hh03012025/internal/model"
+
+// Store сохраняет и восстанавливает полное состояние задач.
+type Store interface {
+	// Load возвращает сохранённые задачи. Отсутствие сохранённого состояния
+	// не является ошибкой: в этом случае возвращается пустая карта.
+	Load() (map[string]*model.Task, error)
+	// Save записывает полное состояние задач.
+	Save(tasks map[string]*model.Task) error
+}
+
+// SkipReporter — хранилище, которое при загрузке пропускает записи, не
+// заменяя ими уже прочитанные (например, повторяющиеся ID), и сообщает их
+// число.
+type SkipReporter interface {
+	// Skipped возвращает число записей, пропущенных последним Load.
+	Skipped() int
+}
+
+// TaskStore — хранилище, умеющее сохранять отдельные задачи при каждом
+// изменении. Менеджер вызывает SaveTask сразу после обновления статуса,
+// поэтому периодическая запись полного состояния для него не нужна.
+type TaskStore interface {
+	Store
+	SaveTask(t *model.Task) error
+	// DeleteTask удаляет задачу и её файлы из хранилища.
+	DeleteTask(id string) error
+}
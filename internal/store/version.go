@@ -0,0 +1,124 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"hh03012025/internal/model"
+)
+
+// SnapshotVersion — текущая версия формата JSON-снапшота. Увеличивается при
+// несовместимом изменении моделей; для каждой предыдущей версии в migrations
+// должна быть функция перехода к следующей.
+const SnapshotVersion = 2
+
+// ErrUnsupportedVersion возвращается при загрузке состояния, записанного
+// более новой версией сервиса.
+var ErrUnsupportedVersion = errors.New("unsupported snapshot version")
+
+// snapshot — конверт JSON-снапшота.
+type snapshot struct {
+	Version int                    `json:"version"`
+	Tasks   map[string]*model.Task `json:"tasks"`
+}
+
+// migrations[v] переводит сырые данные снапшота версии v в версию v+1.
+var migrations = map[int]func(json.RawMessage) (json.RawMessage, error){
+	1: migrateV1,
+}
+
+// migrateV1 оборачивает снапшот версии 1 (карта задач без конверта) в
+// конверт версии 2.
+func migrateV1(data json.RawMessage) (json.RawMessage, error) {
+	return json.Marshal(struct {
+		Version int             `json:"version"`
+		Tasks   json.RawMessage `json:"tasks"`
+	}{2, data})
+}
+
+// snapshotVersion определяет версию сырого снапшота. Снапшоты без поля
+// "version" записаны до появления конверта и имеют версию 1.
+func snapshotVersion(data json.RawMessage) (int, error) {
+	var head map[string]json.RawMessage
+	if err := json.Unmarshal(data, &head); err != nil {
+		return 0, err
+	}
+	raw, ok := head["version"]
+	if !ok {
+		return 1, nil
+	}
+	var v int
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return 1, nil // задача с ID "version" в снапшоте версии 1
+	}
+	return v, nil
+}
+
+// decodeSnapshot разбирает снапшот любой поддерживаемой версии, при
+// необходимости последовательно применяя миграции. Возвращает также ID,
+// повторившиеся в карте задач (см. decodeTasks).
+func decodeSnapshot(data []byte) (tasks map[string]*model.Task, dups []string, err error) {
+	v, err := snapshotVersion(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if v > SnapshotVersion || v < 1 {
+		return nil, nil, fmt.Errorf("%w: %d (supported up to %d)", ErrUnsupportedVersion, v, SnapshotVersion)
+	}
+	for ; v < SnapshotVersion; v++ {
+		if data, err = migrations[v](data); err != nil {
+			return nil, nil, fmt.Errorf("migrate snapshot from version %d: %w", v, err)
+		}
+	}
+	var s struct {
+		Tasks json.RawMessage `json:"tasks"`
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, nil, err
+	}
+	return decodeTasks(s.Tasks)
+}
+
+// decodeTasks разбирает карту задач снапшота. В отличие от json.Unmarshal,
+// повторяющийся ключ (например, после ручного слияния снапшотов) не
+// заменяет уже прочитанную задачу: остаётся первая запись, а ключ
+// возвращается в dups.
+func decodeTasks(data json.RawMessage) (tasks map[string]*model.Task, dups []string, err error) {
+	tasks = map[string]*model.Task{}
+	if len(bytes.TrimSpace(data)) == 0 || bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return tasks, nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return nil, nil, err
+	} else if tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("tasks: expected object, got %v", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := tok.(string)
+		var t *model.Task
+		if err := dec.Decode(&t); err != nil {
+			return nil, nil, fmt.Errorf("task %s: %w", key, err)
+		}
+		if _, dup := tasks[key]; dup {
+			dups = append(dups, key)
+			continue
+		}
+		tasks[key] = t
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	return tasks, dups, nil
+}
+
+// encodeSnapshot сериализует задачи в конверт текущей версии.
+func encodeSnapshot(tasks map[string]*model.Task) ([]byte, error) {
+	return json.MarshalIndent(snapshot{Version: SnapshotVersion, Tasks: tasks}, "", "  ")
+}
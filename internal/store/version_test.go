@@ -0,0 +1,50 @@
+package store
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestDecodeTasks(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantURLs map[string]string // ID задачи → URL её первого файла
+		wantDups []string
+		wantErr  bool
+	}{
+		{"empty", ``, map[string]string{}, nil, false},
+		{"null", `null`, map[string]string{}, nil, false},
+		{"no tasks", `{}`, map[string]string{}, nil, false},
+		{"unique", `{"a":{"id":"a","files":[{"url":"https://h/1"}]},"b":{"id":"b","files":[{"url":"https://h/2"}]}}`,
+			map[string]string{"a": "https://h/1", "b": "https://h/2"}, nil, false},
+		{"duplicate keeps first", `{"a":{"id":"a","files":[{"url":"https://h/1"}]},"a":{"id":"a","files":[{"url":"https://h/2"}]},"a":{"id":"a","files":[{"url":"https://h/3"}]}}`,
+			map[string]string{"a": "https://h/1"}, []string{"a", "a"}, false},
+		{"not an object", `[]`, nil, nil, true},
+		{"malformed task", `{"a":{"id":1}}`, nil, nil, true},
+		{"truncated", `{"a":{"id":"a"}`, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, dups, err := decodeTasks(json.RawMessage(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := map[string]string{}
+			for id, task := range tasks {
+				got[id] = task.Files[0].URL
+			}
+			if !maps.Equal(got, tt.wantURLs) {
+				t.Errorf("tasks = %v, want %v", got, tt.wantURLs)
+			}
+			if !slices.Equal(dups, tt.wantDups) {
+				t.Errorf("dups = %q, want %q", dups, tt.wantDups)
+			}
+		})
+	}
+}
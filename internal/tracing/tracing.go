@@ -0,0 +1,51 @@
+// Package tracing настраивает экспорт трассировок OpenTelemetry.
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ServiceName — имя сервиса в трассировках, если не задан OTEL_SERVICE_NAME.
+const ServiceName = "hh03012025-downloader"
+
+// Enabled сообщает, задан ли адрес коллектора OTLP в переменных окружения
+// OTEL_EXPORTER_OTLP_ENDPOINT или OTEL_EXPORTER_OTLP_TRACES_ENDPOINT.
+func Enabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Setup устанавливает глобальный TracerProvider, экспортирующий спаны по
+// OTLP/HTTP, и пропагатор W3C Trace Context. Экспортёр настраивается
+// стандартными переменными OTEL_EXPORTER_OTLP_* (адрес, заголовки, TLS).
+// Без заданного адреса коллектора (см. Enabled) ничего не делает: спаны
+// создаются no-op реализацией и никуда не отправляются. Возвращаемую функцию
+// нужно вызвать при остановке, чтобы отправить накопленные спаны.
+func Setup(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
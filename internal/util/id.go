@@ -2,18 +2,107 @@ package util
 
 import (
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
-	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
-// GenerateID генерирует случайный 16‑байтовый hex‑идентификатор.
-// Если нет доступа к источнику случайных данных, используется текущее время.
-// Используется для присвоения уникальных идентификаторов задачам.
-func GenerateID() string {
+// MaxIDPrefix — максимальная длина префикса идентификаторов.
+const MaxIDPrefix = 16
+
+// Форматы идентификаторов для NewID.
+const (
+	IDFormatHex  = "hex"  // 32 hex-символа, формат по умолчанию
+	IDFormatUUID = "uuid" // UUID версии 4 в каноническом виде (RFC 4122)
+)
+
+// idCounter различает запасные идентификаторы, полученные в одну наносекунду.
+var idCounter atomic.Uint32
+
+// readRandom — источник случайных данных; подменяется в тестах.
+var readRandom = rand.Read
+
+// randomID возвращает 16 случайных байт. Если нет доступа к источнику
+// случайных данных, байты составляются из текущего времени, PID процесса и
+// счётчика: ID, выданные одним процессом, не повторяются.
+func randomID() []byte {
 	b := make([]byte, 16)
-	if _, err := rand.Read(b); err != nil {
-		return fmt.Sprintf("%d", time.Now().UnixNano())
+	if _, err := readRandom(b); err != nil {
+		binary.BigEndian.PutUint64(b[0:8], uint64(time.Now().UnixNano()))
+		binary.BigEndian.PutUint32(b[8:12], uint32(os.Getpid()))
+		binary.BigEndian.PutUint32(b[12:16], idCounter.Add(1))
+	}
+	return b
+}
+
+// GenerateID генерирует случайный 16‑байтовый hex‑идентификатор с префиксом
+// prefix (например, "task_"), чтобы ID разных сущностей различались в логах.
+// Используется для присвоения уникальных идентификаторов задачам.
+func GenerateID(prefix string) string {
+	return prefix + hex.EncodeToString(randomID())
+}
+
+// GenerateUUID генерирует случайный UUID версии 4 в каноническом виде
+// xxxxxxxx-xxxx-4xxx-yxxx-xxxxxxxxxxxx с префиксом prefix.
+func GenerateUUID(prefix string) string {
+	b := randomID()
+	b[6] = b[6]&0x0f | 0x40 // версия 4
+	b[8] = b[8]&0x3f | 0x80 // вариант RFC 4122
+	h := hex.EncodeToString(b)
+	return prefix + h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// NewID генерирует идентификатор в формате format (IDFormatHex или
+// IDFormatUUID; пустая строка — IDFormatHex) с префиксом prefix.
+func NewID(format, prefix string) string {
+	if format == IDFormatUUID {
+		return GenerateUUID(prefix)
 	}
-	return hex.EncodeToString(b)
+	return GenerateID(prefix)
+}
+
+// ValidIDPrefix сообщает, годится ли prefix в качестве префикса
+// идентификаторов: не длиннее MaxIDPrefix символов из строчных латинских
+// букв, цифр, "_" и "-", чтобы ID оставался безопасным именем каталога и
+// сегментом URL.
+func ValidIDPrefix(prefix string) bool {
+	return len(prefix) <= MaxIDPrefix && strings.Trim(prefix, "abcdefghijklmnopqrstuvwxyz0123456789_-") == ""
+}
+
+// ValidID сообщает, может ли id быть идентификатором, выданным NewID:
+// допустимый префикс (см. ValidIDPrefix) и 32 hex-символа или UUID в нижнем
+// регистре, либо десятичное число, которое выдавали прежние версии при
+// недоступности источника случайных данных. Префикс и формат не сверяются с
+// текущими настройками, чтобы ID, выданные до их смены, оставались
+// корректными. Позволяет отклонить заведомо некорректный ID без поиска задачи.
+func ValidID(id string) bool {
+	if id == "" || len(id) > MaxIDPrefix+36 {
+		return false
+	}
+	if len(id) <= 32 && strings.Trim(id, "0123456789") == "" {
+		return true
+	}
+	if len(id) >= 36 && validUUID(id[len(id)-36:]) && ValidIDPrefix(id[:len(id)-36]) {
+		return true
+	}
+	if len(id) < 32 {
+		return false
+	}
+	prefix, hexPart := id[:len(id)-32], id[len(id)-32:]
+	return ValidIDPrefix(prefix) && isLowerHex(hexPart)
+}
+
+// validUUID сообщает, что s — UUID в каноническом виде в нижнем регистре.
+func validUUID(s string) bool {
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return false
+	}
+	return isLowerHex(s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:])
+}
+
+func isLowerHex(s string) bool {
+	return strings.Trim(s, "0123456789abcdef") == ""
 }
@@ -0,0 +1,157 @@
+package util
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// generateConcurrently выдаёт n идентификаторов из workers горутин и
+// возвращает их.
+func generateConcurrently(t *testing.T, workers, n int, gen func() string) []string {
+	t.Helper()
+	ids := make([]string, workers*n)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range n {
+				ids[w*n+i] = gen()
+			}
+		}()
+	}
+	wg.Wait()
+	return ids
+}
+
+func TestGenerateIDUnique(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback bool
+	}{
+		{"random", false},
+		{"fallback without randomness", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.fallback {
+				saved := readRandom
+				readRandom = func([]byte) (int, error) { return 0, errors.New("no entropy") }
+				t.Cleanup(func() { readRandom = saved })
+			}
+			seen := make(map[string]bool)
+			for _, id := range generateConcurrently(t, 8, 1000, func() string { return GenerateID("task_") }) {
+				if seen[id] {
+					t.Fatalf("duplicate ID %q", id)
+				}
+				seen[id] = true
+				if !strings.HasPrefix(id, "task_") || len(id) != len("task_")+32 || !ValidID(id) {
+					t.Fatalf("malformed ID %q", id)
+				}
+			}
+		})
+	}
+}
+
+func TestValidIDPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   bool
+	}{
+		{"", true},
+		{"task_", true},
+		{"dl-2025", true},
+		{strings.Repeat("a", MaxIDPrefix), true},
+		{strings.Repeat("a", MaxIDPrefix+1), false},
+		{"Task_", false},
+		{"a/b", false},
+		{"a.b", false},
+		{"задача", false},
+	}
+	for _, tt := range tests {
+		if got := ValidIDPrefix(tt.prefix); got != tt.want {
+			t.Errorf("ValidIDPrefix(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestValidID(t *testing.T) {
+	hexID := strings.Repeat("0123456789abcdef", 2)
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{hexID, true},
+		{"task_" + hexID, true},
+		{"1700000000000000000", true},
+		{"", false},
+		{hexID[:31], false},
+		{strings.ToUpper(hexID), false},
+		{"Task_" + hexID, false},
+		{"../" + hexID, false},
+		{strings.Repeat("a", MaxIDPrefix+1) + hexID, false},
+	}
+	for _, tt := range tests {
+		if got := ValidID(tt.id); got != tt.want {
+			t.Errorf("ValidID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateUUID(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, id := range generateConcurrently(t, 4, 500, func() string { return GenerateUUID("dl-") }) {
+		if seen[id] {
+			t.Fatalf("duplicate UUID %q", id)
+		}
+		seen[id] = true
+		u, ok := strings.CutPrefix(id, "dl-")
+		if !ok || !validUUID(u) || !ValidID(id) {
+			t.Fatalf("malformed UUID %q", id)
+		}
+		if u[14] != '4' {
+			t.Errorf("%q: version %c, want 4", id, u[14])
+		}
+		if !strings.ContainsRune("89ab", rune(u[19])) {
+			t.Errorf("%q: variant %c, want RFC 4122", id, u[19])
+		}
+	}
+}
+
+func TestNewID(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantLen int
+	}{
+		{"", 32},
+		{IDFormatHex, 32},
+		{IDFormatUUID, 36},
+	}
+	for _, tt := range tests {
+		id := NewID(tt.format, "p_")
+		if len(id) != len("p_")+tt.wantLen || !strings.HasPrefix(id, "p_") || !ValidID(id) {
+			t.Errorf("NewID(%q) = %q", tt.format, id)
+		}
+	}
+}
+
+func TestValidUUIDID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"123e4567-e89b-42d3-a456-426614174000", true},
+		{"task_123e4567-e89b-42d3-a456-426614174000", true},
+		{"123E4567-E89B-42D3-A456-426614174000", false},
+		{"123e4567e89b-42d3-a456-426614174000-", false},
+		{"123e4567-e89b-42d3-a456-42661417400g", false},
+		{"Task_123e4567-e89b-42d3-a456-426614174000", false},
+	}
+	for _, tt := range tests {
+		if got := ValidID(tt.id); got != tt.want {
+			t.Errorf("ValidID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
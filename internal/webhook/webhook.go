@@ -0,0 +1,78 @@
+// Package webhook отправляет уведомления о событиях задач на URL клиента.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultAttempts — число попыток доставки уведомления.
+const DefaultAttempts = 4
+
+// DefaultBackoff — пауза перед первой повторной попыткой; каждая следующая
+// пауза вдвое длиннее предыдущей.
+const DefaultBackoff = time.Second
+
+// attemptTimeout ограничивает время одной попытки доставки.
+const attemptTimeout = 10 * time.Second
+
+// Sender доставляет уведомления POST‑запросом с JSON‑телом и повторяет
+// попытки с экспоненциальной паузой при ошибках сети и ответах не из 2xx.
+type Sender struct {
+	Client   *http.Client
+	Attempts int
+	Backoff  time.Duration
+}
+
+// NewSender возвращает отправителя с параметрами по умолчанию.
+func NewSender() *Sender {
+	return &Sender{Client: &http.Client{}, Attempts: DefaultAttempts, Backoff: DefaultBackoff}
+}
+
+// Send отправляет payload на url. Возвращает ошибку последней попытки, если
+// ни одна не завершилась ответом 2xx, или ошибку ctx при его отмене.
+func (s *Sender) Send(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	backoff := s.Backoff
+	for attempt := 1; ; attempt++ {
+		err = s.post(ctx, url, body)
+		if err == nil {
+			return nil
+		}
+		if attempt >= s.Attempts {
+			return fmt.Errorf("webhook failed after %d attempts: %w", attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (s *Sender) post(ctx context.Context, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, attemptTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
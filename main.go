@@ -2,15 +2,27 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+
 	"hh03012025/internal/api"
+	"hh03012025/internal/config"
+	"hh03012025/internal/download"
+	"hh03012025/internal/grpcapi"
 	"hh03012025/internal/manager"
+	"hh03012025/internal/metrics"
+	"hh03012025/internal/store"
+	"hh03012025/internal/tracing"
 )
 
 // main — точка входа сервиса загрузки файлов. Здесь настраивается
@@ -20,52 +32,222 @@ import (
 // корректное завершение: при получении сигнала ожидание завершения
 // текущих загрузок и сохранение состояния.
 func main() {
-	// Настройки по умолчанию. Их можно заменить переменными окружения или флагами.
-	downloadDir := "downloads"
-	snapshotFile := "tasks_snapshot.json"
-	workerCount := 5
-	jobQueueSize := 100
+	// Настройки: значения по умолчанию, переменные окружения и флаги.
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fatal("ошибка конфигурации", err)
+	}
+	slog.SetDefault(cfg.Logger())
+
+	// Трассировки OpenTelemetry отправляются, только если задан адрес
+	// коллектора в OTEL_EXPORTER_OTLP_ENDPOINT.
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		fatal("ошибка настройки трассировки", err)
+	}
+
+	// Хранилище состояния: JSON‑снапшот или SQLite.
+	var st store.Store
+	switch cfg.Store {
+	case "sqlite":
+		db, err := store.OpenSQLite(cfg.SQLitePath)
+		if err != nil {
+			fatal("ошибка открытия SQLite", err)
+		}
+		defer db.Close()
+		st = db
+	default:
+		st = store.NewJSONStore(cfg.SnapshotFile, cfg.SnapshotGzip)
+	}
 
 	// Создаём менеджер с буферизированной очередью заданий.
-	mgr := manager.NewManager(jobQueueSize)
+	mcfg := cfg.ManagerConfig()
+	if cfg.TLSCAFile != "" {
+		pool, err := download.LoadCertPool(cfg.TLSCAFile)
+		if err != nil {
+			fatal("ошибка загрузки сертификатов CA", err)
+		}
+		mcfg.RootCAs = pool
+	}
+	mgr := manager.NewManager(cfg.QueueSize, mcfg, st)
+	// Недоступный для записи каталог загрузок — ошибка конфигурации, а не
+	// повод падать каждому скачиванию по отдельности.
+	if err := mgr.CheckDownloadDir(); err != nil {
+		fatal("ошибка каталога загрузок", err)
+	}
+	if err := metrics.Register(prometheus.DefaultRegisterer, mgr.QueueDepth, mgr.TaskCount); err != nil {
+		fatal("ошибка регистрации метрик", err)
+	}
 	// Корневой контекст для воркеров и задачи снапшота. Отмена
 	// распространится на все горутины, использующие этот ctx.
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Восстанавливаем состояние из снапшота и ставим незавершённые файлы в очередь.
-	mgr.LoadFromSnapshot(snapshotFile, downloadDir)
+	// Восстанавливаем состояние из хранилища и ставим незавершённые файлы в очередь.
+	// Нечитаемый снапшот по умолчанию не мешает запуску: сервис стартует без
+	// задач, как раньше, но с -fail-on-snapshot-error завершается, чтобы
+	// оператор не потерял состояние незаметно.
+	skipped, err := mgr.LoadFromSnapshot()
+	if err != nil {
+		if cfg.FailOnSnapshot {
+			fatal("ошибка загрузки снапшота", err)
+		}
+		slog.Error("ошибка загрузки снапшота, запуск без сохранённых задач", "error", err)
+	}
+	if skipped > 0 {
+		slog.Warn("часть задач из снапшота пропущена", "skipped", skipped)
+	}
+	// Удаляем временные файлы скачиваний, прерванных аварийным завершением.
+	if cfg.CleanStaleParts {
+		n, err := mgr.RemoveStaleParts()
+		if err != nil {
+			slog.Error("ошибка очистки временных файлов", "error", err)
+		}
+		if n > 0 || err == nil {
+			slog.Info("удалены устаревшие временные файлы", "count", n)
+		}
+	}
 	// Запускаем воркеры для обработки очереди скачиваний.
-	mgr.StartWorkers(ctx, workerCount, downloadDir)
-	// Периодически сохраняем состояние задач на диск.
-	go mgr.SnapshotLoop(ctx, snapshotFile, 15*time.Second)
+	mgr.StartWorkers(ctx, cfg.Workers)
+	// Периодически сохраняем состояние задач на диск; при нулевом интервале —
+	// только при остановке. У записи снапшотов свой контекст: финальный
+	// снапшот пишется после завершения воркеров, а не вместе с их отменой.
+	snapshotCtx, stopSnapshots := context.WithCancel(context.Background())
+	snapshotDone := make(chan struct{})
+	go func() {
+		mgr.SnapshotLoop(snapshotCtx, cfg.SnapshotInterval)
+		close(snapshotDone)
+	}()
+	// Запускаем отложенные задачи, когда наступает их время.
+	go mgr.ScheduleLoop(ctx)
+	// Удаляем завершённые задачи старше TaskTTL.
+	go mgr.ReapLoop(ctx, cfg.ReapInterval)
 
-	// Настраиваем маршруты HTTP и мидлвар.
+	// Настраиваем маршруты HTTP и мидлвар. Метод и параметры пути задаются
+	// шаблонами ServeMux: на запрос с другим методом он сам отвечает 405, а
+	// WithJSONErrors переводит его 404 и 405 в JSON.
 	mux := http.NewServeMux()
-	mux.HandleFunc("/tasks", api.NewCreateTaskHandler(mgr))
-	mux.HandleFunc("/tasks/", api.NewGetTaskHandler(mgr))
-	handler := api.WithCORS(mux)
-	srv := &http.Server{Addr: ":8080", Handler: handler}
+	mux.HandleFunc("POST /tasks", api.NewCreateTaskHandler(mgr, cfg.MaxRequestBody))
+	mux.HandleFunc("GET /tasks", api.NewListTasksHandler(mgr))
+	mux.HandleFunc("GET /tasks/{id}", api.NewGetTaskHandler(mgr, cfg.MaxPollWait))
+	mux.HandleFunc("POST /tasks/status", api.NewBatchStatusHandler(mgr, cfg.MaxRequestBody))
+	// Потоки событий закрываются при остановке HTTP-сервера, иначе
+	// Shutdown ждал бы их до завершения задач.
+	stopStreams := make(chan struct{})
+	mux.HandleFunc("GET /tasks/{id}/events", api.NewTaskEventsHandler(mgr, stopStreams))
+	mux.HandleFunc("GET /tasks/{id}/files/{index}", api.NewFileHandler(mgr))
+	mux.HandleFunc("POST /tasks/{id}/pause", api.NewPauseTaskHandler(mgr))
+	mux.HandleFunc("POST /tasks/{id}/resume", api.NewResumeTaskHandler(mgr))
+	mux.HandleFunc("POST /tasks/{id}/retry", api.NewRetryTaskHandler(mgr))
+	mux.HandleFunc("POST /tasks/{id}/cancel", api.NewCancelTaskHandler(mgr))
+	mux.HandleFunc("GET /stats", api.NewStatsHandler(mgr))
+	mux.HandleFunc("GET /admin/workers", api.NewWorkersInfoHandler(mgr))
+	mux.HandleFunc("POST /admin/workers", api.NewWorkersHandler(mgr))
+	mux.HandleFunc("POST /admin/snapshot", api.NewSnapshotHandler(mgr))
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", api.NewHealthHandler())
+	mux.HandleFunc("/readyz", api.NewReadyHandler(mgr))
+	handler := api.WithCORS(api.WithAPIKeys(api.WithJSONErrors(mux), cfg.APIKeys), cfg.CORSConfig())
+	srv := &http.Server{Addr: cfg.Addr, Handler: handler}
+	srv.RegisterOnShutdown(func() { close(stopStreams) })
+
+	// gRPC API работает параллельно с HTTP на отдельном адресе.
+	var grpcSrv *grpc.Server
+	if cfg.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			fatal("ошибка запуска gRPC-сервера", err)
+		}
+		grpcSrv = grpcapi.NewServer(mgr, cfg.APIKeys)
+		go func() {
+			slog.Info("запуск gRPC-сервера", "addr", cfg.GRPCAddr)
+			if err := grpcSrv.Serve(lis); err != nil {
+				fatal("ошибка gRPC-сервера", err)
+			}
+		}()
+	}
 
 	// Обработка сигналов для корректного завершения.
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		<-sigCh
-		log.Println("получен сигнал завершения, начинаем корректное завершение")
+		slog.Info("получен сигнал завершения, начинаем корректное завершение")
+		// Сообщаем /readyz, что новые запросы принимать не стоит.
+		mgr.StartDraining()
 		// Прекращаем приём новых соединений.
-		if err := srv.Shutdown(context.Background()); err != nil {
-			log.Printf("ошибка при остановке сервера: %v", err)
+		stopHTTP(srv, cfg.ShutdownTimeout)
+		if grpcSrv != nil {
+			stopGRPC(grpcSrv, cfg.ShutdownTimeout)
 		}
-		// Отменяем контекст, чтобы завершить воркеры и запись снапшота.
+		// Отменяем контекст, чтобы завершить воркеры.
 		cancel()
-		// Ждём завершения активных загрузок.
-		log.Println("ожидаем завершения активных загрузок...")
-		mgr.Wait()
-		log.Println("загрузки завершены, выходим")
+		// Ждём завершения активных загрузок, но не дольше ShutdownTimeout:
+		// зависшее скачивание не должно мешать остановке процесса.
+		slog.Info("ожидаем завершения активных загрузок", "timeout", cfg.ShutdownTimeout)
+		waitCtx, cancelWait := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancelWait()
+		if !mgr.Wait(waitCtx) {
+			slog.Warn("загрузки не завершились вовремя, выходим принудительно",
+				"timeout", cfg.ShutdownTimeout, "tasks", mgr.InFlight())
+		}
+		// Только теперь, когда прерванные скачивания получили итоговый
+		// статус, пишем финальный снапшот и дожидаемся его, прежде чем
+		// закрывать хранилище.
+		stopSnapshots()
+		<-snapshotDone
+		// Отправляем накопленные спаны.
+		flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelFlush()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("ошибка отправки трассировок", "error", err)
+		}
+		slog.Info("завершение работы")
 	}()
 
-	log.Printf("запуск сервера на %s", srv.Addr)
+	slog.Info("запуск сервера", "addr", srv.Addr)
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("ошибка сервера: %v", err)
+		fatal("ошибка сервера", err)
+	}
+	<-shutdownDone
+}
+
+// stopHTTP корректно останавливает HTTP-сервер, дожидаясь завершения
+// запросов не дольше timeout: долгие опросы GET /tasks/{id}?wait= и
+// медленные скачивания файлов клиентами иначе задержали бы остановку,
+// поэтому по истечении timeout оставшиеся соединения закрываются.
+func stopHTTP(s *http.Server, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := s.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("запросы не завершились вовремя, закрываем соединения", "timeout", timeout)
+		err = s.Close()
 	}
+	if err != nil {
+		slog.Error("ошибка при остановке сервера", "error", err)
+	}
+}
+
+// stopGRPC корректно останавливает gRPC-сервер, дожидаясь завершения
+// вызовов не дольше timeout: потоки WatchTask незавершённых задач иначе
+// задержали бы остановку, поэтому по истечении timeout они обрываются.
+func stopGRPC(s *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		s.Stop()
+	}
+}
+
+// fatal логирует ошибку и завершает процесс с ненулевым кодом.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -46,6 +46,8 @@ function translateStatus(status) {
     case 'completed': return 'завершена';
     case 'completed_with_errors': return 'завершена с ошибками';
     case 'error': return 'ошибка';
+    case 'canceled': return 'отменена';
+    case 'paused': return 'приостановлена';
     default: return status;
   }
 }
@@ -209,7 +211,7 @@ async function fetchTaskStatus(id) {
     });
 
     // Если задача завершена — переносим в «Прошедшие» и прекращаем опрос
-    if (data.status === 'completed' || data.status === 'completed_with_errors' || data.status === 'error') {
+    if (data.status === 'completed' || data.status === 'completed_with_errors' || data.status === 'error' || data.status === 'canceled') {
       moveToPast(id, data);
       return;
     }